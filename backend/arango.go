@@ -1,10 +1,12 @@
 package backend
 
 import (
+	"encoding/json"
 	"fmt"
+	"net/url"
+	"strings"
 	"sync"
 	"sync/atomic"
-	"time"
 
 	driver "github.com/arangodb/go-driver"
 	"github.com/arangodb/go-driver/http"
@@ -14,6 +16,12 @@ import (
 	"github.com/kallsyms/go-graph/schema"
 )
 
+// arangoMeta strips the revision from meta, so the same document always has the same (comparable) backend meta
+// however it was read
+func arangoMeta(meta driver.DocumentMeta) driver.DocumentMeta {
+	return driver.DocumentMeta{Key: meta.Key, ID: meta.ID}
+}
+
 type idGenerator struct {
 	prefix  string
 	counter uint64
@@ -31,16 +39,49 @@ type ArangoBackend struct {
 	idGen  idGenerator
 }
 
+const ARANGO_DEFAULT_DB = "go-graph"
+
+func init() {
+	// plain http(s) is kept for connection strings from before backends were picked by scheme
+	for _, scheme := range []string{"arango+http", "arango+https", "http", "https"} {
+		Register(scheme, func(u *url.URL) (Backend, error) {
+			dbName := strings.Trim(u.Path, "/")
+			if dbName == "" {
+				dbName = ARANGO_DEFAULT_DB
+			}
+
+			endpoint := *u
+			endpoint.Scheme = strings.TrimPrefix(u.Scheme, "arango+")
+			endpoint.Path = ""
+			return NewArangoBackend(endpoint.String(), dbName)
+		})
+	}
+}
+
+// NewArangoBackend connects to the arango server at connStr, using the database dbName (which is created if needed).
+// Credentials can be given as userinfo in connStr.
 func NewArangoBackend(connStr string, dbName string) (*ArangoBackend, error) {
+	u, err := url.Parse(connStr)
+	if err != nil {
+		return nil, err
+	}
+	var auth driver.Authentication
+	if u.User != nil {
+		password, _ := u.User.Password()
+		auth = driver.BasicAuthentication(u.User.Username(), password)
+		u.User = nil
+	}
+
 	conn, err := http.NewConnection(http.ConnectionConfig{
-		Endpoints: []string{connStr},
+		Endpoints: []string{u.String()},
 		ConnLimit: -1,
 	})
 	if err != nil {
 		return nil, err
 	}
 	client, err := driver.NewClient(driver.ClientConfig{
-		Connection: conn,
+		Connection:     conn,
+		Authentication: auth,
 	})
 	if err != nil {
 		return nil, err
@@ -76,54 +117,18 @@ func (backend *ArangoBackend) CreateSchema() error {
 	if err != nil {
 		return err
 	}
+
+	edgeDefinitions := make([]driver.EdgeDefinition, len(schema.EdgeDefinitions))
+	for i, def := range schema.EdgeDefinitions {
+		edgeDefinitions[i] = driver.EdgeDefinition{
+			Collection: def.Label,
+			From:       def.From,
+			To:         def.To,
+		}
+	}
+
 	graph, err := backend.db.CreateGraphV2(nil, "code", &driver.CreateGraphOptions{
-		EdgeDefinitions: []driver.EdgeDefinition{
-			{
-				Collection: "Functions",
-				From:       []string{"package"},
-				To:         []string{"function"},
-			},
-			{
-				Collection: "Statement",
-				From:       []string{"function"},
-				To:         []string{"statement"},
-			},
-			{
-				Collection: "Calls",
-				From:       []string{"function"},
-				To:         []string{"functioncall"},
-			},
-			{
-				Collection: "Callee",
-				From:       []string{"functioncall"},
-				To:         []string{"function"},
-			},
-			{
-				Collection: "References",
-				From:       []string{"statement"},
-				To:         []string{"variable"},
-			},
-			{
-				Collection: "Assigns",
-				From:       []string{"statement"},
-				To:         []string{"variable"},
-			},
-			{
-				Collection: "Next",
-				From:       []string{"statement"},
-				To:         []string{"statement"},
-			},
-			{
-				Collection: "FirstStatement",
-				From:       []string{"functioncall"},
-				To:         []string{"statement"},
-			},
-			{
-				Collection: "CallSiteStatement",
-				From:       []string{"functioncall"},
-				To:         []string{"statement"},
-			},
-		},
+		EdgeDefinitions: edgeDefinitions,
 	})
 	if err != nil {
 		return err
@@ -152,6 +157,32 @@ func (backend *ArangoBackend) CreateSchema() error {
 	if err != nil {
 		return err
 	}
+	// not unique: every version of a package has the same symbols. Uniqueness within a package is checked by
+	// PackageFunctions instead
+	_, _, err = functionCol.EnsurePersistentIndex(nil, []string{"Symbol"}, nil)
+	if err != nil {
+		return err
+	}
+
+	typeCol, err := graph.VertexCollection(nil, "type")
+	if err != nil {
+		// programming error
+		panic(err)
+	}
+	_, _, err = typeCol.EnsurePersistentIndex(nil, []string{"Symbol"}, nil)
+	if err != nil {
+		return err
+	}
+
+	constantCol, err := graph.VertexCollection(nil, "constant")
+	if err != nil {
+		// programming error
+		panic(err)
+	}
+	_, _, err = constantCol.EnsurePersistentIndex(nil, []string{"Symbol"}, nil)
+	if err != nil {
+		return err
+	}
 
 	return nil
 }
@@ -179,7 +210,7 @@ func (backend *ArangoBackend) GetPackages() ([]*schema.Package, error) {
 	return pkgs, nil
 }
 
-func (backend *ArangoBackend) GetPackage(tup coordination.PackageTuple) (*schema.Package, bool) {
+func (backend *ArangoBackend) GetPackage(tup coordination.PackageTuple) (*schema.Package, bool, error) {
 	pkg := &schema.Package{
 		SourceURL: tup.Name,
 		Version:   tup.Version,
@@ -187,19 +218,19 @@ func (backend *ArangoBackend) GetPackage(tup coordination.PackageTuple) (*schema
 
 	cursor, err := backend.db.Query(nil, "FOR p IN package FILTER p.SourceURL == @SourceURL AND p.Version == @Version RETURN p", pkg.Properties())
 	if err != nil {
-		panic(err)
+		return nil, false, err
 	}
 	defer cursor.Close()
 
 	meta, err := cursor.ReadDocument(nil, &pkg)
 	if driver.IsNoMoreDocuments(err) {
-		return nil, false
+		return nil, false, nil
 	} else if err != nil {
-		panic(err)
+		return nil, false, err
 	}
 
-	pkg.SetBackendMeta(meta)
-	return pkg, true
+	pkg.SetBackendMeta(arangoMeta(meta))
+	return pkg, true, nil
 }
 
 func (backend *ArangoBackend) CreatePackage(tup coordination.PackageTuple) (*schema.Package, error) {
@@ -217,7 +248,7 @@ func (backend *ArangoBackend) CreatePackage(tup coordination.PackageTuple) (*sch
 	if err != nil {
 		return nil, err
 	}
-	pkg.SetBackendMeta(meta)
+	pkg.SetBackendMeta(arangoMeta(meta))
 
 	return pkg, nil
 }
@@ -242,13 +273,104 @@ func (backend *ArangoBackend) PackageFunctions(pkg *schema.Package) (map[string]
 		}
 
 		f.Package = pkg
-		f.SetBackendMeta(meta)
-		functions[f.Name] = &f
+		f.SetBackendMeta(arangoMeta(meta))
+		if err := addFunction(functions, &f); err != nil {
+			return nil, err
+		}
 	}
 
 	return functions, nil
 }
 
+func (backend *ArangoBackend) PackageTypes(pkg *schema.Package) (map[string]*schema.Type, error) {
+	cursor, err := backend.db.Query(nil, "FOR t IN OUTBOUND @pkg Types RETURN t", map[string]interface{}{
+		"pkg": pkg.GetBackendMeta().(driver.DocumentMeta).ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+
+	types := map[string]*schema.Type{}
+	for {
+		var t schema.Type
+		meta, err := cursor.ReadDocument(nil, &t)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		t.SetBackendMeta(arangoMeta(meta))
+		types[t.Symbol] = &t
+	}
+
+	return types, nil
+}
+
+func (backend *ArangoBackend) PackageFields(pkg *schema.Package) (map[string]*schema.Field, error) {
+	cursor, err := backend.db.Query(nil, "FOR t IN OUTBOUND @pkg Types FOR f IN OUTBOUND t Fields RETURN {type: t.Symbol, field: f}", map[string]interface{}{
+		"pkg": pkg.GetBackendMeta().(driver.DocumentMeta).ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+
+	fields := map[string]*schema.Field{}
+	for {
+		var row struct {
+			Type  string          `json:"type"`
+			Field json.RawMessage `json:"field"`
+		}
+		if _, err := cursor.ReadDocument(nil, &row); driver.IsNoMoreDocuments(err) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		var f schema.Field
+		if err := json.Unmarshal(row.Field, &f); err != nil {
+			return nil, err
+		}
+		var meta driver.DocumentMeta
+		if err := json.Unmarshal(row.Field, &meta); err != nil {
+			return nil, err
+		}
+
+		f.SetBackendMeta(arangoMeta(meta))
+		fields[row.Type+"."+f.Name] = &f
+	}
+
+	return fields, nil
+}
+
+func (backend *ArangoBackend) PackageConstants(pkg *schema.Package) (map[string]*schema.Constant, error) {
+	cursor, err := backend.db.Query(nil, "FOR c IN OUTBOUND @pkg Constants RETURN c", map[string]interface{}{
+		"pkg": pkg.GetBackendMeta().(driver.DocumentMeta).ID,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+
+	constants := map[string]*schema.Constant{}
+	for {
+		var c schema.Constant
+		meta, err := cursor.ReadDocument(nil, &c)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		c.SetBackendMeta(arangoMeta(meta))
+		constants[c.Symbol] = &c
+	}
+
+	return constants, nil
+}
+
 const VERTEX_BATCH_SIZE = 1000
 const EDGE_BATCH_SIZE = 1000
 const BULK_WORKERS = 20
@@ -262,13 +384,18 @@ func (backend *ArangoBackend) flushVBulk(label string, vertices []schema.Vertex)
 	pMaps := make([]map[string]interface{}, len(vertices))
 	for j, v := range vertices {
 		pMaps[j] = v.Properties()
-		key := backend.idGen.Next()
 
-		v.SetBackendMeta(driver.DocumentMeta{
-			Key: key,
-			ID:  driver.DocumentID(label + "/" + key),
-		})
-		pMaps[j]["_key"] = key
+		// keys are kept between retries, so the documents are replaced instead of duplicated
+		meta, ok := v.GetBackendMeta().(driver.DocumentMeta)
+		if !ok {
+			key := backend.idGen.Next()
+			meta = driver.DocumentMeta{
+				Key: key,
+				ID:  driver.DocumentID(label + "/" + key),
+			}
+			v.SetBackendMeta(meta)
+		}
+		pMaps[j]["_key"] = meta.Key
 	}
 
 	var details []string
@@ -294,7 +421,7 @@ func (backend *ArangoBackend) flushVBulk(label string, vertices []schema.Vertex)
 	return nil
 }
 
-func (backend *ArangoBackend) AddVStream(vertices chan schema.Vertex, progressCb func([]schema.Vertex)) *sync.WaitGroup {
+func (backend *ArangoBackend) AddVStream(vertices chan schema.Vertex, progressCb func([]schema.Vertex)) *VertexStream {
 	// init idGenerator, doesn't really matter if this is racey
 	if backend.idGen.prefix == "" {
 		idCol, err := backend.db.Collection(nil, "idgen")
@@ -309,10 +436,9 @@ func (backend *ArangoBackend) AddVStream(vertices chan schema.Vertex, progressCb
 		backend.idGen.prefix = meta.Key
 	}
 
-	var wg sync.WaitGroup
+	stream := &VertexStream{}
 	for i := 0; i < BULK_WORKERS; i++ {
-		wg.Add(1)
-		go func() {
+		stream.Go(func() error {
 			vByLabel := map[string][]schema.Vertex{}
 
 			for {
@@ -321,13 +447,9 @@ func (backend *ArangoBackend) AddVStream(vertices chan schema.Vertex, progressCb
 					stuff := append(vByLabel[label], v)
 
 					if len(vByLabel[label]) > VERTEX_BATCH_SIZE {
-						for {
-							err := backend.flushVBulk(label, stuff)
-							if err == nil {
-								break
-							}
-							logrus.Error(err)
-							time.Sleep(3 * time.Second)
+						if err := retry(func(int) error { return backend.flushVBulk(label, stuff) }); err != nil {
+							Drain(vertices)
+							return err
 						}
 
 						progressCb(stuff)
@@ -341,20 +463,17 @@ func (backend *ArangoBackend) AddVStream(vertices chan schema.Vertex, progressCb
 			}
 
 			for label, stuff := range vByLabel {
-				backend.flushVBulk(label, stuff)
+				if err := retry(func(int) error { return backend.flushVBulk(label, stuff) }); err != nil {
+					return err
+				}
 				progressCb(stuff)
 			}
 
-			wg.Done()
-		}()
+			return nil
+		})
 	}
 
-	return &wg
-}
-
-type ArangoEdge struct {
-	From driver.DocumentID `json:"_from"`
-	To   driver.DocumentID `json:"_to"`
+	return stream
 }
 
 func (backend *ArangoBackend) flushEBulk(label string, edges []schema.Edge) error {
@@ -363,11 +482,14 @@ func (backend *ArangoBackend) flushEBulk(label string, edges []schema.Edge) erro
 		return fmt.Errorf("Error getting edge collection %q: %v", label, err)
 	}
 
-	aEdges := make([]ArangoEdge, len(edges))
+	aEdges := make([]map[string]interface{}, len(edges))
 	for j, edge := range edges {
-		aEdges[j] = ArangoEdge{
-			From: edge.Source.GetBackendMeta().(driver.DocumentMeta).ID,
-			To:   edge.Target.GetBackendMeta().(driver.DocumentMeta).ID,
+		aEdges[j] = map[string]interface{}{
+			"_from": edge.Source.GetBackendMeta().(driver.DocumentMeta).ID,
+			"_to":   edge.Target.GetBackendMeta().(driver.DocumentMeta).ID,
+		}
+		for k, v := range edge.Properties {
+			aEdges[j][k] = v
 		}
 	}
 
@@ -389,15 +511,18 @@ func (backend *ArangoBackend) flushEBulk(label string, edges []schema.Edge) erro
 	return nil
 }
 
-func (backend *ArangoBackend) AddEBulk(allEdges []schema.Edge, progressCb func([]schema.Edge)) {
+func (backend *ArangoBackend) AddEBulk(allEdges []schema.Edge, progressCb func([]schema.Edge)) error {
 	logrus.Infof("Adding %d edges", len(allEdges))
 
 	work := make(chan schema.Edge)
 
 	var wg sync.WaitGroup
+	var errs firstError
 	for i := 0; i < BULK_WORKERS; i++ {
 		wg.Add(1)
 		go func() {
+			defer wg.Done()
+
 			eByLabel := map[string][]schema.Edge{}
 
 			for {
@@ -405,13 +530,11 @@ func (backend *ArangoBackend) AddEBulk(allEdges []schema.Edge, progressCb func([
 					stuff := append(eByLabel[e.Label], e)
 
 					if len(eByLabel[e.Label]) > EDGE_BATCH_SIZE {
-						for {
-							err := backend.flushEBulk(e.Label, stuff)
-							if err == nil {
-								break
+						if err := retry(func(int) error { return backend.flushEBulk(e.Label, stuff) }); err != nil {
+							errs.set(err)
+							for range work {
 							}
-							logrus.Error(err)
-							time.Sleep(3 * time.Second)
+							return
 						}
 
 						progressCb(stuff)
@@ -425,11 +548,12 @@ func (backend *ArangoBackend) AddEBulk(allEdges []schema.Edge, progressCb func([
 			}
 
 			for label, stuff := range eByLabel {
-				backend.flushEBulk(label, stuff)
+				if err := retry(func(int) error { return backend.flushEBulk(label, stuff) }); err != nil {
+					errs.set(err)
+					return
+				}
 				progressCb(stuff)
 			}
-
-			wg.Done()
 		}()
 	}
 
@@ -439,4 +563,179 @@ func (backend *ArangoBackend) AddEBulk(allEdges []schema.Edge, progressCb func([
 
 	close(work)
 	wg.Wait()
+	return errs.get()
+}
+
+// arangoVertex converts a raw vertex document back into the schema type for its collection
+func arangoVertex(doc map[string]interface{}) (schema.Vertex, error) {
+	id, _ := doc["_id"].(string)
+	key, _ := doc["_key"].(string)
+
+	v, err := schema.NewVertex(strings.SplitN(id, "/", 2)[0])
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(buf, v); err != nil {
+		return nil, err
+	}
+
+	v.SetBackendMeta(driver.DocumentMeta{Key: key, ID: driver.DocumentID(id)})
+	return v, nil
+}
+
+func (backend *ArangoBackend) traverse(v schema.Vertex, label string, direction string) ([]schema.Edge, error) {
+	if err := checkEdgeLabel(label); err != nil {
+		return nil, err
+	}
+
+	cursor, err := backend.db.Query(nil, fmt.Sprintf("FOR v, e IN 1..1 %s @start @@col RETURN {v, e}", direction), map[string]interface{}{
+		"start": v.GetBackendMeta().(driver.DocumentMeta).ID,
+		"@col":  label,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close()
+
+	edges := []schema.Edge{}
+	for {
+		var doc struct {
+			V map[string]interface{} `json:"v"`
+			E map[string]interface{} `json:"e"`
+		}
+		_, err := cursor.ReadDocument(nil, &doc)
+		if driver.IsNoMoreDocuments(err) {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		other, err := arangoVertex(doc.V)
+		if err != nil {
+			return nil, err
+		}
+
+		props := map[string]interface{}{}
+		for k, val := range doc.E {
+			if !strings.HasPrefix(k, "_") {
+				props[k] = val
+			}
+		}
+
+		edge := schema.Edge{Source: v, Label: label, Target: other, Properties: props}
+		if direction == "INBOUND" {
+			edge.Source, edge.Target = other, v
+		}
+		edges = append(edges, edge)
+	}
+
+	return edges, nil
+}
+
+func (backend *ArangoBackend) OutEdges(v schema.Vertex, label string) ([]schema.Edge, error) {
+	return backend.traverse(v, label, "OUTBOUND")
+}
+
+func (backend *ArangoBackend) InEdges(v schema.Vertex, label string) ([]schema.Edge, error) {
+	return backend.traverse(v, label, "INBOUND")
+}
+
+func (backend *ArangoBackend) ScanVertices(cb func(schema.Vertex) error) error {
+	for _, label := range schema.VertexLabels {
+		cursor, err := backend.db.Query(nil, "FOR d IN @@col RETURN d", map[string]interface{}{
+			"@col": label,
+		})
+		if err != nil {
+			return err
+		}
+
+		for {
+			var doc map[string]interface{}
+			_, err := cursor.ReadDocument(nil, &doc)
+			if driver.IsNoMoreDocuments(err) {
+				break
+			} else if err != nil {
+				cursor.Close()
+				return err
+			}
+
+			v, err := arangoVertex(doc)
+			if err != nil {
+				cursor.Close()
+				return err
+			}
+			if err := cb(v); err != nil {
+				cursor.Close()
+				return err
+			}
+		}
+		cursor.Close()
+	}
+	return nil
+}
+
+// arangoStub makes an empty vertex which only has its backend meta set, from a document id like "statement/abc-1"
+func arangoStub(id string) (schema.Vertex, error) {
+	parts := strings.SplitN(id, "/", 2)
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("Malformed document id %q", id)
+	}
+
+	v, err := schema.NewVertex(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	v.SetBackendMeta(driver.DocumentMeta{Key: parts[1], ID: driver.DocumentID(id)})
+	return v, nil
+}
+
+func (backend *ArangoBackend) ScanEdges(cb func(schema.Edge) error) error {
+	for _, def := range schema.EdgeDefinitions {
+		cursor, err := backend.db.Query(nil, "FOR d IN @@col RETURN d", map[string]interface{}{
+			"@col": def.Label,
+		})
+		if err != nil {
+			return err
+		}
+
+		for {
+			var doc map[string]interface{}
+			_, err := cursor.ReadDocument(nil, &doc)
+			if driver.IsNoMoreDocuments(err) {
+				break
+			} else if err != nil {
+				cursor.Close()
+				return err
+			}
+
+			edge := schema.Edge{Label: def.Label, Properties: map[string]interface{}{}}
+			from, _ := doc["_from"].(string)
+			to, _ := doc["_to"].(string)
+			if edge.Source, err = arangoStub(from); err != nil {
+				cursor.Close()
+				return err
+			}
+			if edge.Target, err = arangoStub(to); err != nil {
+				cursor.Close()
+				return err
+			}
+			for k, val := range doc {
+				if !strings.HasPrefix(k, "_") {
+					edge.Properties[k] = val
+				}
+			}
+
+			if err := cb(edge); err != nil {
+				cursor.Close()
+				return err
+			}
+		}
+		cursor.Close()
+	}
+	return nil
 }
@@ -1,7 +1,11 @@
 package backend
 
 import (
+	"fmt"
 	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
 
 	"github.com/kallsyms/go-graph/coordination"
 	"github.com/kallsyms/go-graph/schema"
@@ -11,9 +15,125 @@ type Backend interface {
 	DropAll() error
 	CreateSchema() error
 	GetPackages() ([]*schema.Package, error)
-	GetPackage(coordination.PackageTuple) (*schema.Package, bool)
+	// GetPackage looks up the package stored for tup. found is false (with no error) if there isn't one.
+	GetPackage(tup coordination.PackageTuple) (pkg *schema.Package, found bool, err error)
 	CreatePackage(coordination.PackageTuple) (*schema.Package, error)
+	// PackageFunctions returns every function in pkg, keyed by Symbol. Symbols are unique within a package, so finding
+	// two functions with the same one is an error rather than one silently replacing the other.
 	PackageFunctions(pkg *schema.Package) (map[string]*schema.Function, error)
-	AddVStream(vertices chan schema.Vertex, progressCb func([]schema.Vertex)) *sync.WaitGroup
-	AddEBulk(edges []schema.Edge, progressCb func([]schema.Edge))
+	// PackageTypes returns every named type in pkg, keyed by Symbol
+	PackageTypes(pkg *schema.Package) (map[string]*schema.Type, error)
+	// PackageConstants returns every package level constant in pkg, keyed by Symbol
+	PackageConstants(pkg *schema.Package) (map[string]*schema.Constant, error)
+	// PackageFields returns the fields of every named type in pkg, keyed by the type's Symbol and the field's Name
+	// (e.g. net/http.Server.ReadTimeout)
+	PackageFields(pkg *schema.Package) (map[string]*schema.Field, error)
+	// AddVStream stores every vertex sent on vertices (setting its backend meta) until vertices is closed.
+	// Wait on the returned stream for them all to be stored.
+	AddVStream(vertices chan schema.Vertex, progressCb func([]schema.Vertex)) *VertexStream
+	// AddEBulk stores edges, whose sources and targets must already be stored
+	AddEBulk(edges []schema.Edge, progressCb func([]schema.Edge)) error
+}
+
+// VertexStream tracks the workers storing the vertices sent to AddVStream
+type VertexStream struct {
+	wg   sync.WaitGroup
+	errs firstError
+}
+
+// Go runs worker in the background. A worker which fails must still read vertices until they're closed (see Drain),
+// so whoever's sending them isn't blocked.
+func (stream *VertexStream) Go(worker func() error) {
+	stream.wg.Add(1)
+	go func() {
+		defer stream.wg.Done()
+		stream.errs.set(worker())
+	}()
+}
+
+// Wait waits for every worker to finish, and returns the first error any of them failed with
+func (stream *VertexStream) Wait() error {
+	stream.wg.Wait()
+	return stream.errs.get()
+}
+
+// firstError keeps the first error set by any of several workers
+type firstError struct {
+	mtx sync.Mutex
+	err error
+}
+
+func (e *firstError) set(err error) {
+	e.mtx.Lock()
+	if e.err == nil {
+		e.err = err
+	}
+	e.mtx.Unlock()
+}
+
+func (e *firstError) get() error {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	return e.err
+}
+
+// Drain discards the rest of vertices
+func Drain(vertices chan schema.Vertex) {
+	for range vertices {
+	}
+}
+
+// addFunction adds f to functions (keyed by Symbol) for PackageFunctions, failing if another function already has
+// its Symbol
+func addFunction(functions map[string]*schema.Function, f *schema.Function) error {
+	if _, ok := functions[f.Symbol]; ok {
+		return fmt.Errorf("Error: more than one function in the package has symbol %q", f.Symbol)
+	}
+	functions[f.Symbol] = f
+	return nil
+}
+
+// FLUSH_ATTEMPTS is how many times a batch is sent to a server before giving up on it
+const FLUSH_ATTEMPTS = 5
+
+// retry calls flush until it succeeds, or has failed FLUSH_ATTEMPTS times, returning the last error.
+// A failed attempt may have been partially (or even fully) stored, so later attempts (attempt > 0) mustn't duplicate
+// anything.
+func retry(flush func(attempt int) error) error {
+	var err error
+	for attempt := 0; attempt < FLUSH_ATTEMPTS; attempt++ {
+		if attempt > 0 {
+			time.Sleep(3 * time.Second)
+		}
+		if err = flush(attempt); err == nil {
+			return nil
+		}
+		logrus.Error(err)
+	}
+	return err
+}
+
+// Traverser is implemented by backends whose stored graph can be walked one edge at a time from Go.
+// Vertices returned by different calls may be different structs for the same stored vertex, so compare them by
+// their backend meta instead.
+// label is required: every backend returns an error (see checkEdgeLabel) rather than guessing what "" means.
+type Traverser interface {
+	OutEdges(v schema.Vertex, label string) ([]schema.Edge, error)
+	InEdges(v schema.Vertex, label string) ([]schema.Edge, error)
+}
+
+// checkEdgeLabel rejects the empty label passed to a Traverser
+func checkEdgeLabel(label string) error {
+	if label == "" {
+		return fmt.Errorf("An edge label is required to traverse edges")
+	}
+	return nil
+}
+
+// Enumerator is implemented by backends which can list everything they store, e.g. to dump it.
+// Edge sources and targets only need their backend meta set, and it must compare equal to the meta of the matching
+// vertex from ScanVertices.
+type Enumerator interface {
+	ScanVertices(cb func(schema.Vertex) error) error
+	ScanEdges(cb func(schema.Edge) error) error
 }
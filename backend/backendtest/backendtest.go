@@ -0,0 +1,682 @@
+// Package backendtest is a conformance suite for backend.Backend implementations.
+//
+// A backend's own tests only need to say how to get a fresh instance of it:
+//
+//	func TestConformance(t *testing.T) {
+//		backendtest.Run(t, func(t *testing.T) backend.Backend {
+//			b, err := NewMyBackend(t.TempDir())
+//			if err != nil {
+//				t.Fatal(err)
+//			}
+//			return b
+//		})
+//	}
+//
+// Edges are checked through backend.Traverser and backend.Enumerator if the backend implements them, and only through
+// PackageFunctions, PackageTypes and PackageConstants otherwise.
+package backendtest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	gbackend "github.com/kallsyms/go-graph/backend"
+	"github.com/kallsyms/go-graph/coordination"
+	"github.com/kallsyms/go-graph/schema"
+)
+
+// Factory returns a new, empty backend with its schema already created.
+type Factory func(t *testing.T) gbackend.Backend
+
+// Run runs every conformance test against backends made by newBackend, each test getting its own backend.
+func Run(t *testing.T, newBackend Factory) {
+	tests := []struct {
+		name string
+		fn   func(*testing.T, gbackend.Backend)
+	}{
+		{"PackageDedup", testPackageDedup},
+		{"PackageFunctions", testPackageFunctions},
+		{"PackageFunctionsDuplicateSymbol", testPackageFunctionsDuplicateSymbol},
+		{"PackageTypes", testPackageTypes},
+		{"PackageConstants", testPackageConstants},
+		{"PackageFields", testPackageFields},
+		{"ConcurrentAddVStream", testConcurrentAddVStream},
+		{"AddEBulk", testAddEBulk},
+		{"DropAll", testDropAll},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newBackend(t))
+		})
+	}
+}
+
+// addVertices streams vs into backend, failing t unless every one of them is reported done
+func addVertices(t *testing.T, backend gbackend.Backend, vs ...schema.Vertex) {
+	t.Helper()
+
+	var mtx sync.Mutex
+	done := 0
+	stream := make(chan schema.Vertex)
+	workers := backend.AddVStream(stream, func(batch []schema.Vertex) {
+		mtx.Lock()
+		done += len(batch)
+		mtx.Unlock()
+	})
+	for _, v := range vs {
+		stream <- v
+	}
+	close(stream)
+	if err := workers.Wait(); err != nil {
+		t.Fatalf("AddVStream: %v", err)
+	}
+
+	if done != len(vs) {
+		t.Fatalf("AddVStream reported %d vertices done, want %d", done, len(vs))
+	}
+}
+
+// addEdges adds es to backend, failing t unless every one of them is reported done
+func addEdges(t *testing.T, backend gbackend.Backend, es ...schema.Edge) {
+	t.Helper()
+
+	var mtx sync.Mutex
+	done := 0
+	err := backend.AddEBulk(es, func(batch []schema.Edge) {
+		mtx.Lock()
+		done += len(batch)
+		mtx.Unlock()
+	})
+	if err != nil {
+		t.Fatalf("AddEBulk: %v", err)
+	}
+
+	if done != len(es) {
+		t.Fatalf("AddEBulk reported %d edges done, want %d", done, len(es))
+	}
+}
+
+// getPackage calls GetPackage, failing t if it returns an error
+func getPackage(t *testing.T, backend gbackend.Backend, tup coordination.PackageTuple) (*schema.Package, bool) {
+	t.Helper()
+
+	pkg, found, err := backend.GetPackage(tup)
+	if err != nil {
+		t.Fatalf("GetPackage(%v): %v", tup, err)
+	}
+	return pkg, found
+}
+
+func testPackageDedup(t *testing.T, backend gbackend.Backend) {
+	v1 := coordination.PackageTuple{Name: "example.com/dedup", Version: "v1.0.0"}
+	v2 := coordination.PackageTuple{Name: "example.com/dedup", Version: "v2.0.0"}
+
+	if _, ok := getPackage(t, backend, v1); ok {
+		t.Fatalf("GetPackage(%v) found a package in an empty backend", v1)
+	}
+
+	created, err := backend.CreatePackage(v1)
+	if err != nil {
+		t.Fatalf("CreatePackage(%v): %v", v1, err)
+	}
+	if created.SourceURL != v1.Name || created.Version != v1.Version {
+		t.Errorf("CreatePackage(%v) = %s@%s", v1, created.SourceURL, created.Version)
+	}
+	if created.GetBackendMeta() == nil {
+		t.Fatalf("CreatePackage(%v) didn't set backend meta", v1)
+	}
+
+	found, ok := getPackage(t, backend, v1)
+	if !ok {
+		t.Fatalf("GetPackage(%v) didn't find the package just created", v1)
+	}
+	if found.SourceURL != v1.Name || found.Version != v1.Version {
+		t.Errorf("GetPackage(%v) = %s@%s", v1, found.SourceURL, found.Version)
+	}
+	if found.GetBackendMeta() != created.GetBackendMeta() {
+		t.Errorf("GetPackage(%v) meta = %v, CreatePackage meta = %v", v1, found.GetBackendMeta(), created.GetBackendMeta())
+	}
+
+	// a different version of the same package is a different package
+	if _, ok := getPackage(t, backend, v2); ok {
+		t.Errorf("GetPackage(%v) found a package, but only %v was created", v2, v1)
+	}
+	other, err := backend.CreatePackage(v2)
+	if err != nil {
+		t.Fatalf("CreatePackage(%v): %v", v2, err)
+	}
+	if other.GetBackendMeta() == created.GetBackendMeta() {
+		t.Errorf("%v and %v have the same backend meta %v", v1, v2, other.GetBackendMeta())
+	}
+
+	pkgs, err := backend.GetPackages()
+	if err != nil {
+		t.Fatalf("GetPackages: %v", err)
+	}
+	seen := map[coordination.PackageTuple]bool{}
+	for _, pkg := range pkgs {
+		seen[coordination.PackageTuple{Name: pkg.SourceURL, Version: pkg.Version}] = true
+	}
+	if len(pkgs) != 2 || !seen[v1] || !seen[v2] {
+		t.Errorf("GetPackages returned %d packages (%v), want %v and %v", len(pkgs), seen, v1, v2)
+	}
+}
+
+func testPackageFunctions(t *testing.T, backend gbackend.Backend) {
+	pkg, err := backend.CreatePackage(coordination.PackageTuple{Name: "example.com/funcs", Version: "v0.1.0"})
+	if err != nil {
+		t.Fatalf("CreatePackage: %v", err)
+	}
+	otherPkg, err := backend.CreatePackage(coordination.PackageTuple{Name: "example.com/other", Version: "v0.1.0"})
+	if err != nil {
+		t.Fatalf("CreatePackage: %v", err)
+	}
+
+	empty, err := backend.PackageFunctions(pkg)
+	if err != nil {
+		t.Fatalf("PackageFunctions on a package with no functions: %v", err)
+	}
+	if len(empty) != 0 {
+		t.Errorf("PackageFunctions on a package with no functions = %v", empty)
+	}
+
+	// methods with the same name on different receivers must not collide
+	decls := []*schema.Function{
+		{Name: "main", Symbol: "example.com/funcs.main", Signature: "func()"},
+		{Name: "init", Symbol: "example.com/funcs.init.0", Signature: "func()"},
+		{Name: "init", Symbol: "example.com/funcs.init.1", Signature: "func()"},
+		{Name: "String", Receiver: "T", Symbol: "example.com/funcs.T.String", Signature: "func() string"},
+		{Name: "String", Receiver: "*U", Symbol: "example.com/funcs.(*U).String", Signature: "func() string"},
+		{Name: "Close", Receiver: "T", Symbol: "example.com/funcs.T.Close", Signature: "func() error"},
+		{Name: "Close", Receiver: "U", Symbol: "example.com/funcs.U.Close", Signature: "func() error"},
+		{Name: "_", Receiver: "T", Symbol: "example.com/funcs.T._.0", Signature: "func()"},
+		{Name: "_", Receiver: "T", Symbol: "example.com/funcs.T._.1", Signature: "func()"},
+	}
+	functions := map[string]*schema.Function{}
+	vs := []schema.Vertex{}
+	es := []schema.Edge{}
+	for _, f := range decls {
+		f.Package = pkg
+		functions[f.Symbol] = f
+		vs = append(vs, f)
+		es = append(es, schema.Edge{Source: pkg, Label: "Functions", Target: f})
+	}
+	unrelated := &schema.Function{Name: "main", Symbol: "example.com/other.main", Signature: "func()", Package: otherPkg}
+	vs = append(vs, unrelated)
+	es = append(es, schema.Edge{Source: otherPkg, Label: "Functions", Target: unrelated})
+
+	addVertices(t, backend, vs...)
+	addEdges(t, backend, es...)
+
+	// use the package as GetPackage returns it, like processPackage does when relinking
+	found, ok := getPackage(t, backend, coordination.PackageTuple{Name: pkg.SourceURL, Version: pkg.Version})
+	if !ok {
+		t.Fatalf("GetPackage didn't find %s@%s", pkg.SourceURL, pkg.Version)
+	}
+
+	got, err := backend.PackageFunctions(found)
+	if err != nil {
+		t.Fatalf("PackageFunctions: %v", err)
+	}
+	if len(got) != len(decls) {
+		t.Errorf("PackageFunctions returned %d functions, want %d: %v", len(got), len(decls), got)
+	}
+	for symbol, want := range functions {
+		f, ok := got[symbol]
+		if !ok {
+			t.Errorf("PackageFunctions is missing %q", symbol)
+			continue
+		}
+		if f.Symbol != symbol || f.Name != want.Name || f.Receiver != want.Receiver || f.Signature != want.Signature {
+			t.Errorf("PackageFunctions[%q] = %+v, want %+v", symbol, f.Properties(), want.Properties())
+		}
+		if f.GetBackendMeta() != want.GetBackendMeta() {
+			t.Errorf("PackageFunctions[%q] meta = %v, want %v", symbol, f.GetBackendMeta(), want.GetBackendMeta())
+		}
+	}
+}
+
+func testPackageFunctionsDuplicateSymbol(t *testing.T, backend gbackend.Backend) {
+	pkg, err := backend.CreatePackage(coordination.PackageTuple{Name: "example.com/dup", Version: "v0.1.0"})
+	if err != nil {
+		t.Fatalf("CreatePackage: %v", err)
+	}
+
+	// ingest never does this, but if it ever did, relinking has to notice rather than pick one of them
+	a := &schema.Function{Name: "Close", Receiver: "T", Symbol: "example.com/dup.T.Close", Signature: "func() error"}
+	b := &schema.Function{Name: "Close", Receiver: "T", Symbol: "example.com/dup.T.Close", Signature: "func()"}
+	addVertices(t, backend, a, b)
+	addEdges(t, backend,
+		schema.Edge{Source: pkg, Label: "Functions", Target: a},
+		schema.Edge{Source: pkg, Label: "Functions", Target: b},
+	)
+
+	found, ok := getPackage(t, backend, coordination.PackageTuple{Name: pkg.SourceURL, Version: pkg.Version})
+	if !ok {
+		t.Fatalf("GetPackage didn't find %s@%s", pkg.SourceURL, pkg.Version)
+	}
+	if got, err := backend.PackageFunctions(found); err == nil {
+		t.Errorf("PackageFunctions with a duplicate symbol = %v, want an error", got)
+	}
+}
+
+func testPackageTypes(t *testing.T, backend gbackend.Backend) {
+	pkg, err := backend.CreatePackage(coordination.PackageTuple{Name: "example.com/types", Version: "v0.1.0"})
+	if err != nil {
+		t.Fatalf("CreatePackage: %v", err)
+	}
+	otherPkg, err := backend.CreatePackage(coordination.PackageTuple{Name: "example.com/other", Version: "v0.1.0"})
+	if err != nil {
+		t.Fatalf("CreatePackage: %v", err)
+	}
+
+	empty, err := backend.PackageTypes(pkg)
+	if err != nil {
+		t.Fatalf("PackageTypes on a package with no types: %v", err)
+	}
+	if len(empty) != 0 {
+		t.Errorf("PackageTypes on a package with no types = %v", empty)
+	}
+
+	decls := []*schema.Type{
+		{Name: "T", Symbol: "example.com/types.T", Kind: "struct", Underlying: "struct{sync.Mutex; N int}"},
+		{Name: "I", Symbol: "example.com/types.I", Kind: "interface", Underlying: "interface{String() string}"},
+		{Name: "A", Symbol: "example.com/types.A", Kind: "alias", Underlying: "example.com/types.T"},
+	}
+	types := map[string]*schema.Type{}
+	vs := []schema.Vertex{}
+	es := []schema.Edge{}
+	for _, typ := range decls {
+		types[typ.Symbol] = typ
+		vs = append(vs, typ)
+		es = append(es, schema.Edge{Source: pkg, Label: "Types", Target: typ})
+	}
+	// neither the fields nor the other package's type are types of pkg
+	field := &schema.Field{Name: "N", Type: "int", Index: 1}
+	unrelated := &schema.Type{Name: "T", Symbol: "example.com/other.T", Kind: "basic", Underlying: "int"}
+	vs = append(vs, field, unrelated)
+	es = append(es,
+		schema.Edge{Source: decls[0], Label: "Fields", Target: field},
+		schema.Edge{Source: otherPkg, Label: "Types", Target: unrelated},
+	)
+
+	addVertices(t, backend, vs...)
+	addEdges(t, backend, es...)
+
+	found, ok := getPackage(t, backend, coordination.PackageTuple{Name: pkg.SourceURL, Version: pkg.Version})
+	if !ok {
+		t.Fatalf("GetPackage didn't find %s@%s", pkg.SourceURL, pkg.Version)
+	}
+
+	got, err := backend.PackageTypes(found)
+	if err != nil {
+		t.Fatalf("PackageTypes: %v", err)
+	}
+	if len(got) != len(decls) {
+		t.Errorf("PackageTypes returned %d types, want %d: %v", len(got), len(decls), got)
+	}
+	for symbol, want := range types {
+		typ, ok := got[symbol]
+		if !ok {
+			t.Errorf("PackageTypes is missing %q", symbol)
+			continue
+		}
+		if typ.Symbol != symbol || typ.Name != want.Name || typ.Kind != want.Kind || typ.Underlying != want.Underlying {
+			t.Errorf("PackageTypes[%q] = %+v, want %+v", symbol, typ.Properties(), want.Properties())
+		}
+		if typ.GetBackendMeta() != want.GetBackendMeta() {
+			t.Errorf("PackageTypes[%q] meta = %v, want %v", symbol, typ.GetBackendMeta(), want.GetBackendMeta())
+		}
+	}
+}
+
+func testPackageConstants(t *testing.T, backend gbackend.Backend) {
+	pkg, err := backend.CreatePackage(coordination.PackageTuple{Name: "example.com/consts", Version: "v0.1.0"})
+	if err != nil {
+		t.Fatalf("CreatePackage: %v", err)
+	}
+
+	decls := []*schema.Constant{
+		{Name: "N", Symbol: "example.com/consts.N", Type: "untyped int", Value: "42"},
+		{Name: "S", Symbol: "example.com/consts.S", Type: "string", Value: `"a \"quoted\" string"`},
+	}
+	constants := map[string]*schema.Constant{}
+	vs := []schema.Vertex{}
+	es := []schema.Edge{}
+	for _, c := range decls {
+		constants[c.Symbol] = c
+		vs = append(vs, c)
+		es = append(es, schema.Edge{Source: pkg, Label: "Constants", Target: c})
+	}
+	// local constants aren't linked to their package
+	vs = append(vs, &schema.Constant{Name: "local", Type: "untyped bool", Value: "true"})
+
+	addVertices(t, backend, vs...)
+	addEdges(t, backend, es...)
+
+	found, ok := getPackage(t, backend, coordination.PackageTuple{Name: pkg.SourceURL, Version: pkg.Version})
+	if !ok {
+		t.Fatalf("GetPackage didn't find %s@%s", pkg.SourceURL, pkg.Version)
+	}
+
+	got, err := backend.PackageConstants(found)
+	if err != nil {
+		t.Fatalf("PackageConstants: %v", err)
+	}
+	if len(got) != len(decls) {
+		t.Errorf("PackageConstants returned %d constants, want %d: %v", len(got), len(decls), got)
+	}
+	for symbol, want := range constants {
+		c, ok := got[symbol]
+		if !ok {
+			t.Errorf("PackageConstants is missing %q", symbol)
+			continue
+		}
+		if c.Name != want.Name || c.Type != want.Type || c.Value != want.Value {
+			t.Errorf("PackageConstants[%q] = %+v, want %+v", symbol, c.Properties(), want.Properties())
+		}
+		if c.GetBackendMeta() != want.GetBackendMeta() {
+			t.Errorf("PackageConstants[%q] meta = %v, want %v", symbol, c.GetBackendMeta(), want.GetBackendMeta())
+		}
+	}
+}
+
+func testPackageFields(t *testing.T, backend gbackend.Backend) {
+	pkg, err := backend.CreatePackage(coordination.PackageTuple{Name: "example.com/fields", Version: "v0.1.0"})
+	if err != nil {
+		t.Fatalf("CreatePackage: %v", err)
+	}
+
+	server := &schema.Type{Name: "Server", Symbol: "example.com/fields.Server", Kind: "struct", Underlying: "struct{sync.Mutex; Addr string}"}
+	conf := &schema.Type{Name: "Conf", Symbol: "example.com/fields.Conf", Kind: "struct", Underlying: "struct{Addr string}"}
+	// type Defaults Conf shares Conf's fields
+	defaults := &schema.Type{Name: "Defaults", Symbol: "example.com/fields.Defaults", Kind: "struct", Underlying: "struct{Addr string}"}
+	fields := map[string]*schema.Field{
+		"example.com/fields.Server.Mutex": {Name: "Mutex", Type: "sync.Mutex", Embedded: true, Index: 0},
+		"example.com/fields.Server.Addr":  {Name: "Addr", Type: "string", Index: 1, Tag: `json:"addr"`},
+		"example.com/fields.Conf.Addr":    {Name: "Addr", Type: "string", Index: 0},
+	}
+	fields["example.com/fields.Defaults.Addr"] = fields["example.com/fields.Conf.Addr"]
+	// a field of an anonymous struct has no named type to be found through
+	anonymous := &schema.Field{Name: "X", Type: "int"}
+
+	addVertices(t, backend, server, conf, defaults, anonymous,
+		fields["example.com/fields.Server.Mutex"], fields["example.com/fields.Server.Addr"], fields["example.com/fields.Conf.Addr"])
+	addEdges(t, backend,
+		schema.Edge{Source: pkg, Label: "Types", Target: server},
+		schema.Edge{Source: pkg, Label: "Types", Target: conf},
+		schema.Edge{Source: pkg, Label: "Types", Target: defaults},
+		schema.Edge{Source: server, Label: "Fields", Target: fields["example.com/fields.Server.Mutex"]},
+		schema.Edge{Source: server, Label: "Fields", Target: fields["example.com/fields.Server.Addr"]},
+		schema.Edge{Source: conf, Label: "Fields", Target: fields["example.com/fields.Conf.Addr"]},
+		schema.Edge{Source: defaults, Label: "Fields", Target: fields["example.com/fields.Conf.Addr"]},
+	)
+
+	found, ok := getPackage(t, backend, coordination.PackageTuple{Name: pkg.SourceURL, Version: pkg.Version})
+	if !ok {
+		t.Fatalf("GetPackage didn't find %s@%s", pkg.SourceURL, pkg.Version)
+	}
+
+	got, err := backend.PackageFields(found)
+	if err != nil {
+		t.Fatalf("PackageFields: %v", err)
+	}
+	if len(got) != len(fields) {
+		t.Errorf("PackageFields returned %d fields, want %d: %v", len(got), len(fields), got)
+	}
+	for key, want := range fields {
+		f, ok := got[key]
+		if !ok {
+			t.Errorf("PackageFields is missing %q", key)
+			continue
+		}
+		if f.Name != want.Name || f.Type != want.Type || f.Embedded != want.Embedded || f.Index != want.Index || f.Tag != want.Tag {
+			t.Errorf("PackageFields[%q] = %+v, want %+v", key, f.Properties(), want.Properties())
+		}
+		if f.GetBackendMeta() != want.GetBackendMeta() {
+			t.Errorf("PackageFields[%q] meta = %v, want %v", key, f.GetBackendMeta(), want.GetBackendMeta())
+		}
+	}
+}
+
+func testConcurrentAddVStream(t *testing.T, backend gbackend.Backend) {
+	const producers = 8
+	const perProducer = 2500
+
+	var mtx sync.Mutex
+	done := 0
+	stream := make(chan schema.Vertex, 64)
+	workers := backend.AddVStream(stream, func(batch []schema.Vertex) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		for _, v := range batch {
+			if v.GetBackendMeta() == nil {
+				t.Errorf("%s %v reported done without backend meta", v.Label(), v)
+			}
+		}
+		done += len(batch)
+	})
+
+	all := make([][]schema.Vertex, producers)
+	var producerWG sync.WaitGroup
+	for p := 0; p < producers; p++ {
+		producerWG.Add(1)
+		go func(p int) {
+			defer producerWG.Done()
+			for i := 0; i < perProducer; i++ {
+				var v schema.Vertex
+				if i%2 == 0 {
+					v = &schema.Statement{File: fmt.Sprintf("p%d.go", p), Offset: i, Text: "x++", ASTType: "*ast.IncDecStmt"}
+				} else {
+					v = &schema.Variable{Name: fmt.Sprintf("v%d_%d", p, i), Type: "int"}
+				}
+				all[p] = append(all[p], v)
+				stream <- v
+			}
+		}(p)
+	}
+	producerWG.Wait()
+	close(stream)
+	if err := workers.Wait(); err != nil {
+		t.Fatalf("AddVStream: %v", err)
+	}
+
+	if done != producers*perProducer {
+		t.Fatalf("AddVStream reported %d vertices done, want %d", done, producers*perProducer)
+	}
+
+	metas := map[interface{}]schema.Vertex{}
+	for _, vs := range all {
+		for _, v := range vs {
+			meta := v.GetBackendMeta()
+			if meta == nil {
+				t.Fatalf("%s %v has no backend meta after AddVStream finished", v.Label(), v)
+			}
+			if prev, dup := metas[meta]; dup {
+				t.Fatalf("%v and %v were both given backend meta %v", prev, v, meta)
+			}
+			metas[meta] = v
+		}
+	}
+
+	if e, ok := backend.(gbackend.Enumerator); ok {
+		count := 0
+		err := e.ScanVertices(func(v schema.Vertex) error {
+			if _, ok := metas[v.GetBackendMeta()]; !ok {
+				return fmt.Errorf("scanned %s %v with unknown backend meta %v", v.Label(), v, v.GetBackendMeta())
+			}
+			count++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("ScanVertices: %v", err)
+		}
+		if count != len(metas) {
+			t.Errorf("ScanVertices returned %d vertices, want %d", count, len(metas))
+		}
+	}
+}
+
+func testAddEBulk(t *testing.T, backend gbackend.Backend) {
+	// a little CFG with a loop: s0 -> s1 -> s2 -> s1, s2 -> s3, all referencing x
+	stmts := []*schema.Statement{}
+	for i := 0; i < 4; i++ {
+		stmts = append(stmts, &schema.Statement{File: "cfg.go", Offset: i * 10, Text: fmt.Sprintf("s%d", i), ASTType: "*ast.ExprStmt"})
+	}
+	x := &schema.Variable{Name: "x", Type: "int"}
+
+	vs := []schema.Vertex{x}
+	for _, s := range stmts {
+		vs = append(vs, s)
+	}
+	addVertices(t, backend, vs...)
+
+	next := func(from, to int, back bool) schema.Edge {
+		return schema.Edge{Source: stmts[from], Label: "Next", Target: stmts[to], Properties: map[string]interface{}{"isBackEdge": back}}
+	}
+	es := []schema.Edge{
+		next(0, 1, false),
+		next(1, 2, false),
+		next(2, 1, true),
+		next(2, 3, false),
+	}
+	for _, s := range stmts {
+		es = append(es, schema.Edge{Source: s, Label: "References", Target: x})
+	}
+	addEdges(t, backend, es...)
+
+	if tr, ok := backend.(gbackend.Traverser); ok {
+		out, err := tr.OutEdges(stmts[2], "Next")
+		if err != nil {
+			t.Fatalf("OutEdges: %v", err)
+		}
+		if len(out) != 2 {
+			t.Fatalf("s2 has %d outgoing Next edges, want 2: %v", len(out), out)
+		}
+		for _, e := range out {
+			if e.Label != "Next" {
+				t.Errorf("OutEdges(s2, Next) returned a %q edge", e.Label)
+			}
+			if e.Source.GetBackendMeta() != stmts[2].GetBackendMeta() {
+				t.Errorf("OutEdges(s2, Next) returned an edge from %v", e.Source.GetBackendMeta())
+			}
+
+			target, ok := e.Target.(*schema.Statement)
+			if !ok {
+				t.Fatalf("Next edge from s2 points to a %s", e.Target.Label())
+			}
+			back, _ := e.Properties["isBackEdge"].(bool)
+			switch target.Text {
+			case "s1":
+				if target.GetBackendMeta() != stmts[1].GetBackendMeta() || !back {
+					t.Errorf("s2 -> s1 edge resolved to %v (want %v), isBackEdge = %v", target.GetBackendMeta(), stmts[1].GetBackendMeta(), back)
+				}
+			case "s3":
+				if target.GetBackendMeta() != stmts[3].GetBackendMeta() || back {
+					t.Errorf("s2 -> s3 edge resolved to %v (want %v), isBackEdge = %v", target.GetBackendMeta(), stmts[3].GetBackendMeta(), back)
+				}
+			default:
+				t.Errorf("Next edge from s2 points to %q", target.Text)
+			}
+		}
+
+		in, err := tr.InEdges(x, "References")
+		if err != nil {
+			t.Fatalf("InEdges: %v", err)
+		}
+		if len(in) != len(stmts) {
+			t.Errorf("x has %d incoming References edges, want %d", len(in), len(stmts))
+		}
+		for _, e := range in {
+			if e.Target.GetBackendMeta() != x.GetBackendMeta() {
+				t.Errorf("InEdges(x, References) returned an edge to %v", e.Target.GetBackendMeta())
+			}
+		}
+
+		none, err := tr.OutEdges(stmts[3], "Next")
+		if err != nil {
+			t.Fatalf("OutEdges: %v", err)
+		}
+		if len(none) != 0 {
+			t.Errorf("s3 has %d outgoing Next edges, want none", len(none))
+		}
+
+		if _, err := tr.OutEdges(stmts[0], ""); err == nil {
+			t.Errorf("OutEdges with an empty label didn't fail")
+		}
+		if _, err := tr.InEdges(x, ""); err == nil {
+			t.Errorf("InEdges with an empty label didn't fail")
+		}
+	}
+
+	if e, ok := backend.(gbackend.Enumerator); ok {
+		want := map[string]int{}
+		for _, edge := range es {
+			want[fmt.Sprintf("%v %s %v", edge.Source.GetBackendMeta(), edge.Label, edge.Target.GetBackendMeta())]++
+		}
+
+		got := map[string]int{}
+		err := e.ScanEdges(func(edge schema.Edge) error {
+			got[fmt.Sprintf("%v %s %v", edge.Source.GetBackendMeta(), edge.Label, edge.Target.GetBackendMeta())]++
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("ScanEdges: %v", err)
+		}
+
+		for k, n := range want {
+			if got[k] != n {
+				t.Errorf("ScanEdges returned edge %s %d times, want %d", k, got[k], n)
+			}
+		}
+		if len(got) != len(want) {
+			t.Errorf("ScanEdges returned %d distinct edges, want %d", len(got), len(want))
+		}
+	}
+}
+
+// testDropAll checks that the same backend can be reused after dropping everything and recreating the schema
+func testDropAll(t *testing.T, backend gbackend.Backend) {
+	tup := coordination.PackageTuple{Name: "example.com/drop", Version: "v1.0.0"}
+	if _, err := backend.CreatePackage(tup); err != nil {
+		t.Fatalf("CreatePackage(%v): %v", tup, err)
+	}
+	addVertices(t, backend, &schema.Function{Name: "F", Symbol: "example.com/drop.F"})
+
+	if err := backend.DropAll(); err != nil {
+		t.Fatalf("DropAll: %v", err)
+	}
+	if err := backend.CreateSchema(); err != nil {
+		t.Fatalf("CreateSchema after DropAll: %v", err)
+	}
+
+	pkgs, err := backend.GetPackages()
+	if err != nil {
+		t.Fatalf("GetPackages: %v", err)
+	}
+	if len(pkgs) != 0 {
+		t.Errorf("GetPackages after DropAll = %d packages, want 0", len(pkgs))
+	}
+	if _, ok := getPackage(t, backend, tup); ok {
+		t.Errorf("GetPackage(%v) found a dropped package", tup)
+	}
+
+	pkg, err := backend.CreatePackage(tup)
+	if err != nil {
+		t.Fatalf("CreatePackage(%v) after DropAll: %v", tup, err)
+	}
+	fn := &schema.Function{Name: "F", Symbol: "example.com/drop.F"}
+	addVertices(t, backend, fn)
+	addEdges(t, backend, schema.Edge{Source: pkg, Label: "Functions", Target: fn})
+
+	functions, err := backend.PackageFunctions(pkg)
+	if err != nil {
+		t.Fatalf("PackageFunctions: %v", err)
+	}
+	if len(functions) != 1 {
+		t.Errorf("PackageFunctions after DropAll = %d functions, want 1", len(functions))
+	}
+}
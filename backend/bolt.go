@@ -0,0 +1,471 @@
+package backend
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	bolt "go.etcd.io/bbolt"
+
+	"github.com/kallsyms/go-graph/coordination"
+	"github.com/kallsyms/go-graph/schema"
+)
+
+// BoltID is the backend meta attached to vertices stored in a BoltBackend.
+type BoltID uint64
+
+var (
+	// id -> boltVertex
+	boltVerticesBucket = []byte("vertices")
+	// src id | edge label | 0 | edge seq -> dst id | properties
+	boltOutBucket = []byte("out")
+	// dst id | edge label | 0 | edge seq -> src id | properties
+	boltInBucket = []byte("in")
+	// SourceURL | 0 | Version -> id
+	boltPackagesBucket = []byte("packages")
+)
+
+type boltVertex struct {
+	Label      string                 `json:"label"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+// BoltBackend stores the graph in a single bbolt file, so ingests persist without running a DB server.
+// Edges are stored as adjacency lists in both directions, keyed by vertex id then edge label, so following all edges
+// with a given label from a vertex is a single prefix scan.
+type BoltBackend struct {
+	db *bolt.DB
+}
+
+func init() {
+	Register("file", func(u *url.URL) (Backend, error) {
+		return NewBoltBackend(urlPath(u))
+	})
+}
+
+func NewBoltBackend(path string) (*BoltBackend, error) {
+	db, err := bolt.Open(path, 0644, &bolt.Options{Timeout: 5 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+
+	backend := &BoltBackend{db}
+	if err := backend.CreateSchema(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return backend, nil
+}
+
+func boltKey(id uint64) []byte {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], id)
+	return b[:]
+}
+
+func boltAdjPrefix(id uint64, label string) []byte {
+	prefix := append(boltKey(id), label...)
+	return append(prefix, 0)
+}
+
+func boltPackageKey(tup coordination.PackageTuple) []byte {
+	return []byte(tup.Name + "\x00" + tup.Version)
+}
+
+func (backend *BoltBackend) DropAll() error {
+	err := backend.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltVerticesBucket, boltOutBucket, boltInBucket, boltPackagesBucket} {
+			if err := tx.DeleteBucket(name); err != nil && err != bolt.ErrBucketNotFound {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	// everything else assumes the buckets exist, so leave empty ones behind
+	return backend.CreateSchema()
+}
+
+func (backend *BoltBackend) CreateSchema() error {
+	return backend.db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltVerticesBucket, boltOutBucket, boltInBucket, boltPackagesBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func boltDecodeVertex(id uint64, raw []byte) (schema.Vertex, error) {
+	var bv struct {
+		Label      string          `json:"label"`
+		Properties json.RawMessage `json:"properties"`
+	}
+	if err := json.Unmarshal(raw, &bv); err != nil {
+		return nil, err
+	}
+
+	v, err := schema.NewVertex(bv.Label)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(bv.Properties, v); err != nil {
+		return nil, err
+	}
+	v.SetBackendMeta(BoltID(id))
+	return v, nil
+}
+
+func boltGetVertex(tx *bolt.Tx, id uint64) (schema.Vertex, error) {
+	raw := tx.Bucket(boltVerticesBucket).Get(boltKey(id))
+	if raw == nil {
+		return nil, fmt.Errorf("No vertex with id %d", id)
+	}
+	return boltDecodeVertex(id, raw)
+}
+
+func boltPutVertex(tx *bolt.Tx, v schema.Vertex) (uint64, error) {
+	vertices := tx.Bucket(boltVerticesBucket)
+	id, err := vertices.NextSequence()
+	if err != nil {
+		return 0, err
+	}
+
+	raw, err := json.Marshal(boltVertex{v.Label(), v.Properties()})
+	if err != nil {
+		return 0, err
+	}
+	if err := vertices.Put(boltKey(id), raw); err != nil {
+		return 0, err
+	}
+
+	return id, nil
+}
+
+func (backend *BoltBackend) GetPackages() ([]*schema.Package, error) {
+	pkgs := []*schema.Package{}
+	err := backend.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltPackagesBucket).ForEach(func(_, idRaw []byte) error {
+			v, err := boltGetVertex(tx, binary.BigEndian.Uint64(idRaw))
+			if err != nil {
+				return err
+			}
+			pkgs = append(pkgs, v.(*schema.Package))
+			return nil
+		})
+	})
+	return pkgs, err
+}
+
+func (backend *BoltBackend) GetPackage(tup coordination.PackageTuple) (*schema.Package, bool, error) {
+	var pkg *schema.Package
+	err := backend.db.View(func(tx *bolt.Tx) error {
+		idRaw := tx.Bucket(boltPackagesBucket).Get(boltPackageKey(tup))
+		if idRaw == nil {
+			return nil
+		}
+
+		pkg = &schema.Package{
+			SourceURL: tup.Name,
+			Version:   tup.Version,
+		}
+		pkg.SetBackendMeta(BoltID(binary.BigEndian.Uint64(idRaw)))
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	return pkg, pkg != nil, nil
+}
+
+func (backend *BoltBackend) CreatePackage(tup coordination.PackageTuple) (*schema.Package, error) {
+	pkg := &schema.Package{
+		SourceURL: tup.Name,
+		Version:   tup.Version,
+	}
+
+	err := backend.db.Update(func(tx *bolt.Tx) error {
+		pkgIdx := tx.Bucket(boltPackagesBucket)
+		if pkgIdx.Get(boltPackageKey(tup)) != nil {
+			return fmt.Errorf("Package %v already exists", tup)
+		}
+
+		id, err := boltPutVertex(tx, pkg)
+		if err != nil {
+			return err
+		}
+		pkg.SetBackendMeta(BoltID(id))
+
+		return pkgIdx.Put(boltPackageKey(tup), boltKey(id))
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return pkg, nil
+}
+
+func (backend *BoltBackend) PackageFunctions(pkg *schema.Package) (map[string]*schema.Function, error) {
+	edges, err := backend.OutEdges(pkg, "Functions")
+	if err != nil {
+		return nil, err
+	}
+
+	functions := map[string]*schema.Function{}
+	for _, edge := range edges {
+		if f, ok := edge.Target.(*schema.Function); ok {
+			f.Package = pkg
+			if err := addFunction(functions, f); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return functions, nil
+}
+
+func (backend *BoltBackend) PackageTypes(pkg *schema.Package) (map[string]*schema.Type, error) {
+	edges, err := backend.OutEdges(pkg, "Types")
+	if err != nil {
+		return nil, err
+	}
+
+	types := map[string]*schema.Type{}
+	for _, edge := range edges {
+		if t, ok := edge.Target.(*schema.Type); ok {
+			types[t.Symbol] = t
+		}
+	}
+
+	return types, nil
+}
+
+func (backend *BoltBackend) PackageFields(pkg *schema.Package) (map[string]*schema.Field, error) {
+	typeEdges, err := backend.OutEdges(pkg, "Types")
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]*schema.Field{}
+	for _, typeEdge := range typeEdges {
+		t, ok := typeEdge.Target.(*schema.Type)
+		if !ok {
+			continue
+		}
+		edges, err := backend.OutEdges(t, "Fields")
+		if err != nil {
+			return nil, err
+		}
+		for _, edge := range edges {
+			if f, ok := edge.Target.(*schema.Field); ok {
+				fields[t.Symbol+"."+f.Name] = f
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+func (backend *BoltBackend) PackageConstants(pkg *schema.Package) (map[string]*schema.Constant, error) {
+	edges, err := backend.OutEdges(pkg, "Constants")
+	if err != nil {
+		return nil, err
+	}
+
+	constants := map[string]*schema.Constant{}
+	for _, edge := range edges {
+		if c, ok := edge.Target.(*schema.Constant); ok {
+			constants[c.Symbol] = c
+		}
+	}
+
+	return constants, nil
+}
+
+func (backend *BoltBackend) AddVStream(vertices chan schema.Vertex, progressCb func([]schema.Vertex)) *VertexStream {
+	stream := &VertexStream{}
+	stream.Go(func() error {
+		flush := func(batch []schema.Vertex) error {
+			err := backend.db.Update(func(tx *bolt.Tx) error {
+				for _, v := range batch {
+					id, err := boltPutVertex(tx, v)
+					if err != nil {
+						return err
+					}
+					v.SetBackendMeta(BoltID(id))
+				}
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("Error inserting vertices: %v", err)
+			}
+			progressCb(batch)
+			return nil
+		}
+
+		batch := []schema.Vertex{}
+		for v := range vertices {
+			batch = append(batch, v)
+			if len(batch) >= VERTEX_BATCH_SIZE {
+				if err := flush(batch); err != nil {
+					Drain(vertices)
+					return err
+				}
+				batch = []schema.Vertex{}
+			}
+		}
+
+		if len(batch) > 0 {
+			return flush(batch)
+		}
+		return nil
+	})
+
+	return stream
+}
+
+func boltPutEdge(tx *bolt.Tx, edge schema.Edge) error {
+	src, ok := edge.Source.GetBackendMeta().(BoltID)
+	if !ok {
+		return fmt.Errorf("Edge %q source %v has no id", edge.Label, edge.Source)
+	}
+	dst, ok := edge.Target.GetBackendMeta().(BoltID)
+	if !ok {
+		return fmt.Errorf("Edge %q target %v has no id", edge.Label, edge.Target)
+	}
+
+	props, err := json.Marshal(edge.Properties)
+	if err != nil {
+		return err
+	}
+
+	out := tx.Bucket(boltOutBucket)
+	seq, err := out.NextSequence()
+	if err != nil {
+		return err
+	}
+
+	outKey := append(boltAdjPrefix(uint64(src), edge.Label), boltKey(seq)...)
+	if err := out.Put(outKey, append(boltKey(uint64(dst)), props...)); err != nil {
+		return err
+	}
+
+	inKey := append(boltAdjPrefix(uint64(dst), edge.Label), boltKey(seq)...)
+	return tx.Bucket(boltInBucket).Put(inKey, append(boltKey(uint64(src)), props...))
+}
+
+func (backend *BoltBackend) AddEBulk(edges []schema.Edge, progressCb func([]schema.Edge)) error {
+	logrus.Infof("Adding %d edges", len(edges))
+
+	for start := 0; start < len(edges); start += EDGE_BATCH_SIZE {
+		end := start + EDGE_BATCH_SIZE
+		if end > len(edges) {
+			end = len(edges)
+		}
+		batch := edges[start:end]
+
+		err := backend.db.Update(func(tx *bolt.Tx) error {
+			for _, edge := range batch {
+				if err := boltPutEdge(tx, edge); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("Error inserting edges: %v", err)
+		}
+		progressCb(batch)
+	}
+	return nil
+}
+
+// traverse prefix scans the adjacency bucket for all edges with label from v
+func (backend *BoltBackend) traverse(v schema.Vertex, label string, bucket []byte) ([]schema.Edge, error) {
+	if err := checkEdgeLabel(label); err != nil {
+		return nil, err
+	}
+
+	id, ok := v.GetBackendMeta().(BoltID)
+	if !ok {
+		return nil, fmt.Errorf("Vertex %v is not in this backend", v)
+	}
+
+	edges := []schema.Edge{}
+	err := backend.db.View(func(tx *bolt.Tx) error {
+		prefix := boltAdjPrefix(uint64(id), label)
+		c := tx.Bucket(bucket).Cursor()
+		for k, val := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, val = c.Next() {
+			other, err := boltGetVertex(tx, binary.BigEndian.Uint64(val[:8]))
+			if err != nil {
+				return err
+			}
+
+			edge := schema.Edge{Source: v, Label: label, Target: other}
+			if err := json.Unmarshal(val[8:], &edge.Properties); err != nil {
+				return err
+			}
+			if bytes.Equal(bucket, boltInBucket) {
+				edge.Source, edge.Target = other, v
+			}
+			edges = append(edges, edge)
+		}
+		return nil
+	})
+
+	return edges, err
+}
+
+func (backend *BoltBackend) OutEdges(v schema.Vertex, label string) ([]schema.Edge, error) {
+	return backend.traverse(v, label, boltOutBucket)
+}
+
+func (backend *BoltBackend) InEdges(v schema.Vertex, label string) ([]schema.Edge, error) {
+	return backend.traverse(v, label, boltInBucket)
+}
+
+func (backend *BoltBackend) ScanVertices(cb func(schema.Vertex) error) error {
+	return backend.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltVerticesBucket).ForEach(func(k, raw []byte) error {
+			v, err := boltDecodeVertex(binary.BigEndian.Uint64(k), raw)
+			if err != nil {
+				return err
+			}
+			return cb(v)
+		})
+	})
+}
+
+func (backend *BoltBackend) ScanEdges(cb func(schema.Edge) error) error {
+	return backend.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltOutBucket).ForEach(func(k, val []byte) error {
+			// src id | label | 0 | seq
+			label := string(k[8 : len(k)-9])
+
+			src, err := boltGetVertex(tx, binary.BigEndian.Uint64(k[:8]))
+			if err != nil {
+				return err
+			}
+			dst, err := boltGetVertex(tx, binary.BigEndian.Uint64(val[:8]))
+			if err != nil {
+				return err
+			}
+
+			edge := schema.Edge{Source: src, Label: label, Target: dst}
+			if err := json.Unmarshal(val[8:], &edge.Properties); err != nil {
+				return err
+			}
+			return cb(edge)
+		})
+	})
+}
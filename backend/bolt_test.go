@@ -0,0 +1,43 @@
+package backend_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	gbackend "github.com/kallsyms/go-graph/backend"
+	"github.com/kallsyms/go-graph/backend/backendtest"
+	"github.com/kallsyms/go-graph/coordination"
+)
+
+func TestBoltConformance(t *testing.T) {
+	backendtest.Run(t, func(t *testing.T) gbackend.Backend {
+		b, err := gbackend.NewBoltBackend(filepath.Join(t.TempDir(), "graph.bolt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	})
+}
+
+func TestBoltWriteAfterDropAll(t *testing.T) {
+	b, err := gbackend.NewBoltBackend(filepath.Join(t.TempDir(), "graph.bolt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tup := coordination.PackageTuple{Name: "example.com/drop", Version: "v1.0.0"}
+	if _, err := b.CreatePackage(tup); err != nil {
+		t.Fatalf("CreatePackage(%v): %v", tup, err)
+	}
+	if err := b.DropAll(); err != nil {
+		t.Fatalf("DropAll: %v", err)
+	}
+
+	// without calling CreateSchema again
+	if _, err := b.CreatePackage(tup); err != nil {
+		t.Fatalf("CreatePackage(%v) after DropAll: %v", tup, err)
+	}
+	if _, ok, err := b.GetPackage(tup); err != nil || !ok {
+		t.Errorf("GetPackage(%v) didn't find the package created after DropAll: %v", tup, err)
+	}
+}
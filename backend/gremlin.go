@@ -0,0 +1,946 @@
+package backend
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kallsyms/go-graph/coordination"
+	"github.com/kallsyms/go-graph/schema"
+)
+
+// GraphSON 1.0 is untyped, so results (and ids) decode as plain JSON values
+const GREMLIN_MIME_TYPE = "application/vnd.gremlin-v1.0+json"
+
+// Edges are inserted as one long chained traversal, so keep batches small enough for the server to compile quickly
+const GREMLIN_EDGE_BATCH_SIZE = 100
+
+// GREMLIN_TIMEOUT is how long to wait for the server to answer a request before giving up on the connection
+const GREMLIN_TIMEOUT = 5 * time.Minute
+
+// GREMLIN_ID_PROPERTY is set on every vertex and edge to an id picked by go-graph, so that retrying an insert which may
+// have partially gone through doesn't duplicate anything. Index it on servers which need indexes for lookups.
+const GREMLIN_ID_PROPERTY = "goGraphID"
+
+// GremlinID is the backend meta attached to vertices stored in a GremlinBackend.
+// The wrapped value is whatever id the server handed back (a number for TinkerGraph).
+type GremlinID struct {
+	ID interface{}
+}
+
+type gremlinRequest struct {
+	RequestID string                 `json:"requestId"`
+	Op        string                 `json:"op"`
+	Processor string                 `json:"processor"`
+	Args      map[string]interface{} `json:"args"`
+}
+
+type gremlinResponse struct {
+	RequestID string `json:"requestId"`
+	Status    struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"status"`
+	Result struct {
+		Data json.RawMessage `json:"data"`
+	} `json:"result"`
+}
+
+type gremlinResult struct {
+	data []json.RawMessage
+	err  error
+}
+
+// GremlinBackend talks to a Gremlin Server (TinkerGraph, JanusGraph, Neptune, ...) over its websocket protocol.
+type GremlinBackend struct {
+	endpoint string
+
+	connMtx sync.Mutex
+	conn    *gremlinConn
+}
+
+// gremlinConn is one websocket to the server. Once it fails it's never used again, see GremlinBackend.connection.
+type gremlinConn struct {
+	ws       *websocket.Conn
+	writeMtx sync.Mutex
+
+	pendingMtx sync.Mutex
+	pending    map[string]*pendingGremlinRequest
+	// set (with pendingMtx held) once reading from ws has failed, after which nothing will answer new requests
+	closed error
+}
+
+type pendingGremlinRequest struct {
+	data []json.RawMessage
+	done chan gremlinResult
+}
+
+func init() {
+	// plain ws(s) is the ingester's default -db
+	for _, scheme := range []string{"gremlin+ws", "gremlin+wss", "ws", "wss"} {
+		Register(scheme, func(u *url.URL) (Backend, error) {
+			endpoint := *u
+			endpoint.Scheme = strings.TrimPrefix(u.Scheme, "gremlin+")
+			return NewGremlinBackend(endpoint.String())
+		})
+	}
+}
+
+func NewGremlinBackend(connStr string) (*GremlinBackend, error) {
+	u, err := url.Parse(connStr)
+	if err != nil {
+		return nil, err
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/gremlin"
+	}
+
+	backend := &GremlinBackend{
+		endpoint: u.String(),
+	}
+	// connect now, so a bad -db fails straight away
+	if backend.conn, err = dialGremlin(backend.endpoint); err != nil {
+		return nil, err
+	}
+
+	return backend, nil
+}
+
+func dialGremlin(endpoint string) (*gremlinConn, error) {
+	ws, _, err := websocket.DefaultDialer.Dial(endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	conn := &gremlinConn{
+		ws:      ws,
+		pending: map[string]*pendingGremlinRequest{},
+	}
+	go conn.readLoop()
+
+	return conn, nil
+}
+
+// connection returns the current connection to the server, redialing if it has failed
+func (backend *GremlinBackend) connection() (*gremlinConn, error) {
+	backend.connMtx.Lock()
+	defer backend.connMtx.Unlock()
+
+	if err := backend.conn.err(); err != nil {
+		logrus.Warnf("Reconnecting to gremlin server after: %v", err)
+		conn, err := dialGremlin(backend.endpoint)
+		if err != nil {
+			return nil, err
+		}
+		backend.conn = conn
+	}
+	return backend.conn, nil
+}
+
+func newUUID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		panic(err)
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
+
+// err returns why conn has failed, or nil if it's still usable
+func (conn *gremlinConn) err() error {
+	conn.pendingMtx.Lock()
+	defer conn.pendingMtx.Unlock()
+	return conn.closed
+}
+
+func (conn *gremlinConn) readLoop() {
+	for {
+		_, msg, err := conn.ws.ReadMessage()
+		if err != nil {
+			conn.fail(fmt.Errorf("Gremlin connection closed: %v", err))
+			return
+		}
+
+		var resp gremlinResponse
+		if err := json.Unmarshal(msg, &resp); err != nil {
+			logrus.Errorf("Unable to decode gremlin response: %v", err)
+			continue
+		}
+
+		conn.pendingMtx.Lock()
+		req, ok := conn.pending[resp.RequestID]
+		if !ok {
+			conn.pendingMtx.Unlock()
+			logrus.Warnf("Gremlin response for unknown request %q", resp.RequestID)
+			continue
+		}
+
+		var chunk []json.RawMessage
+		if len(resp.Result.Data) > 0 && !bytes.Equal(resp.Result.Data, []byte("null")) {
+			if err := json.Unmarshal(resp.Result.Data, &chunk); err != nil {
+				// single (non-list) result
+				chunk = []json.RawMessage{resp.Result.Data}
+			}
+		}
+		req.data = append(req.data, chunk...)
+
+		switch resp.Status.Code {
+		case 206:
+			// partial content, more to come
+		case 200, 204:
+			req.done <- gremlinResult{data: req.data}
+			delete(conn.pending, resp.RequestID)
+		default:
+			req.done <- gremlinResult{err: fmt.Errorf("Gremlin error %d: %s", resp.Status.Code, resp.Status.Message)}
+			delete(conn.pending, resp.RequestID)
+		}
+		conn.pendingMtx.Unlock()
+	}
+}
+
+// submit evaluates script on the server and returns the (fully streamed) results.
+// If the connection has failed, a new one is made, so callers retrying after an error don't reuse a dead one.
+func (backend *GremlinBackend) submit(script string, bindings map[string]interface{}) ([]json.RawMessage, error) {
+	conn, err := backend.connection()
+	if err != nil {
+		return nil, err
+	}
+	return conn.submit(script, bindings)
+}
+
+func (conn *gremlinConn) submit(script string, bindings map[string]interface{}) ([]json.RawMessage, error) {
+	req := gremlinRequest{
+		RequestID: newUUID(),
+		Op:        "eval",
+		Processor: "",
+		Args: map[string]interface{}{
+			"gremlin":  script,
+			"bindings": bindings,
+			"language": "gremlin-groovy",
+		},
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Requests are prefixed with the length of the mime type, then the mime type itself
+	msg := append([]byte{byte(len(GREMLIN_MIME_TYPE))}, GREMLIN_MIME_TYPE...)
+	msg = append(msg, body...)
+
+	pending := &pendingGremlinRequest{done: make(chan gremlinResult, 1)}
+	conn.pendingMtx.Lock()
+	if conn.closed != nil {
+		conn.pendingMtx.Unlock()
+		return nil, conn.closed
+	}
+	conn.pending[req.RequestID] = pending
+	conn.pendingMtx.Unlock()
+
+	conn.writeMtx.Lock()
+	err = conn.ws.WriteMessage(websocket.BinaryMessage, msg)
+	conn.writeMtx.Unlock()
+	if err != nil {
+		conn.forget(req.RequestID)
+		return nil, err
+	}
+
+	select {
+	case result := <-pending.done:
+		return result.data, result.err
+	case <-time.After(GREMLIN_TIMEOUT):
+		conn.forget(req.RequestID)
+		// the server may never answer on this connection, so give up on it (and everything else waiting on it)
+		err := fmt.Errorf("Gremlin request timed out after %v", GREMLIN_TIMEOUT)
+		conn.fail(err)
+		return nil, err
+	}
+}
+
+// fail closes conn, failing every request waiting on it and any made later with err (or the error it failed with
+// first)
+func (conn *gremlinConn) fail(err error) {
+	conn.pendingMtx.Lock()
+	if conn.closed == nil {
+		conn.closed = err
+	}
+	for id, req := range conn.pending {
+		req.done <- gremlinResult{err: conn.closed}
+		delete(conn.pending, id)
+	}
+	conn.pendingMtx.Unlock()
+
+	conn.ws.Close()
+}
+
+func (conn *gremlinConn) forget(requestID string) {
+	conn.pendingMtx.Lock()
+	delete(conn.pending, requestID)
+	conn.pendingMtx.Unlock()
+}
+
+func decodeGremlin(raw json.RawMessage, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// gremlinProjection builds a `project` step returning the id and each property in keys as a flat map
+func gremlinProjection(keys []string) string {
+	var sb strings.Builder
+	sb.WriteString(".project('id'")
+	for _, k := range keys {
+		fmt.Fprintf(&sb, ", '%s'", k)
+	}
+	sb.WriteString(").by(id())")
+	for _, k := range keys {
+		fmt.Fprintf(&sb, ".by('%s')", k)
+	}
+	return sb.String()
+}
+
+func sortedKeys(props map[string]interface{}) []string {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// readVertices decodes projected vertices (see gremlinProjection) into the structs returned by newV
+func readVertices(data []json.RawMessage, newV func() schema.Vertex) ([]schema.Vertex, error) {
+	vertices := []schema.Vertex{}
+	for _, raw := range data {
+		var id struct {
+			ID interface{} `json:"id"`
+		}
+		if err := decodeGremlin(raw, &id); err != nil {
+			return nil, err
+		}
+
+		v := newV()
+		if err := decodeGremlin(raw, v); err != nil {
+			return nil, err
+		}
+		v.SetBackendMeta(GremlinID{id.ID})
+		vertices = append(vertices, v)
+	}
+	return vertices, nil
+}
+
+func (backend *GremlinBackend) DropAll() error {
+	_, err := backend.submit("g.V().drop()", nil)
+	return err
+}
+
+func (backend *GremlinBackend) CreateSchema() error {
+	// TinkerGraph is schemaless, and index management differs between every other gremlin implementation,
+	// so leave that to whoever set the server up.
+	return nil
+}
+
+func (backend *GremlinBackend) GetPackages() ([]*schema.Package, error) {
+	data, err := backend.submit("g.V().hasLabel('package')"+gremlinProjection(sortedKeys((&schema.Package{}).Properties())), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	vertices, err := readVertices(data, func() schema.Vertex { return &schema.Package{} })
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs := make([]*schema.Package, len(vertices))
+	for i, v := range vertices {
+		pkgs[i] = v.(*schema.Package)
+	}
+	return pkgs, nil
+}
+
+func (backend *GremlinBackend) GetPackage(tup coordination.PackageTuple) (*schema.Package, bool, error) {
+	data, err := backend.submit(
+		"g.V().hasLabel('package').has('SourceURL', SourceURL).has('Version', Version).id()",
+		map[string]interface{}{
+			"SourceURL": tup.Name,
+			"Version":   tup.Version,
+		},
+	)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(data) == 0 {
+		return nil, false, nil
+	}
+
+	var id interface{}
+	if err := decodeGremlin(data[0], &id); err != nil {
+		return nil, false, err
+	}
+
+	pkg := &schema.Package{
+		SourceURL: tup.Name,
+		Version:   tup.Version,
+	}
+	pkg.SetBackendMeta(GremlinID{id})
+	return pkg, true, nil
+}
+
+func (backend *GremlinBackend) CreatePackage(tup coordination.PackageTuple) (*schema.Package, error) {
+	pkg := &schema.Package{
+		SourceURL: tup.Name,
+		Version:   tup.Version,
+	}
+
+	ids, err := backend.addVertices(pkg.Label(), []schema.Vertex{pkg}, []string{newUUID()})
+	if err != nil {
+		return nil, err
+	}
+	pkg.SetBackendMeta(GremlinID{ids[0]})
+
+	return pkg, nil
+}
+
+func (backend *GremlinBackend) PackageFunctions(pkg *schema.Package) (map[string]*schema.Function, error) {
+	data, err := backend.submit(
+		"g.V(pkg).out('Functions')"+gremlinProjection(sortedKeys((&schema.Function{}).Properties())),
+		map[string]interface{}{
+			"pkg": pkg.GetBackendMeta().(GremlinID).ID,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	vertices, err := readVertices(data, func() schema.Vertex { return &schema.Function{} })
+	if err != nil {
+		return nil, err
+	}
+
+	functions := map[string]*schema.Function{}
+	for _, v := range vertices {
+		f := v.(*schema.Function)
+		f.Package = pkg
+		if err := addFunction(functions, f); err != nil {
+			return nil, err
+		}
+	}
+
+	return functions, nil
+}
+
+func (backend *GremlinBackend) PackageTypes(pkg *schema.Package) (map[string]*schema.Type, error) {
+	data, err := backend.submit(
+		"g.V(pkg).out('Types')"+gremlinProjection(sortedKeys((&schema.Type{}).Properties())),
+		map[string]interface{}{
+			"pkg": pkg.GetBackendMeta().(GremlinID).ID,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	vertices, err := readVertices(data, func() schema.Vertex { return &schema.Type{} })
+	if err != nil {
+		return nil, err
+	}
+
+	types := map[string]*schema.Type{}
+	for _, v := range vertices {
+		t := v.(*schema.Type)
+		types[t.Symbol] = t
+	}
+
+	return types, nil
+}
+
+func (backend *GremlinBackend) PackageFields(pkg *schema.Package) (map[string]*schema.Field, error) {
+	types, err := backend.PackageTypes(pkg)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]*schema.Field{}
+	for symbol, t := range types {
+		data, err := backend.submit(
+			"g.V(typ).out('Fields')"+gremlinProjection(sortedKeys((&schema.Field{}).Properties())),
+			map[string]interface{}{
+				"typ": t.GetBackendMeta().(GremlinID).ID,
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		vertices, err := readVertices(data, func() schema.Vertex { return &schema.Field{} })
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range vertices {
+			f := v.(*schema.Field)
+			fields[symbol+"."+f.Name] = f
+		}
+	}
+
+	return fields, nil
+}
+
+func (backend *GremlinBackend) PackageConstants(pkg *schema.Package) (map[string]*schema.Constant, error) {
+	data, err := backend.submit(
+		"g.V(pkg).out('Constants')"+gremlinProjection(sortedKeys((&schema.Constant{}).Properties())),
+		map[string]interface{}{
+			"pkg": pkg.GetBackendMeta().(GremlinID).ID,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	vertices, err := readVertices(data, func() schema.Vertex { return &schema.Constant{} })
+	if err != nil {
+		return nil, err
+	}
+
+	constants := map[string]*schema.Constant{}
+	for _, v := range vertices {
+		c := v.(*schema.Constant)
+		constants[c.Symbol] = c
+	}
+
+	return constants, nil
+}
+
+// newUUIDs returns n ids for GREMLIN_ID_PROPERTY
+func newUUIDs(n int) []string {
+	ids := make([]string, n)
+	for i := range ids {
+		ids[i] = newUUID()
+	}
+	return ids
+}
+
+// addVertices inserts vertices (which must all have the given label) in one request, returning their new ids in order.
+// gids are their GREMLIN_ID_PROPERTY.
+func (backend *GremlinBackend) addVertices(label string, vertices []schema.Vertex, gids []string) ([]interface{}, error) {
+	rows := make([]map[string]interface{}, len(vertices))
+	for i, v := range vertices {
+		rows[i] = v.Properties()
+		rows[i][GREMLIN_ID_PROPERTY] = gids[i]
+	}
+
+	var sb strings.Builder
+	sb.WriteString("g.inject(rows).unfold().as('r').addV(lbl)")
+	for _, k := range sortedKeys(rows[0]) {
+		fmt.Fprintf(&sb, ".property('%s', select('r').select('%s'))", k, k)
+	}
+	sb.WriteString(".id()")
+
+	data, err := backend.submit(sb.String(), map[string]interface{}{
+		"rows": rows,
+		"lbl":  label,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != len(vertices) {
+		return nil, fmt.Errorf("Expected %d ids back from gremlin but got %d", len(vertices), len(data))
+	}
+
+	ids := make([]interface{}, len(data))
+	for i, raw := range data {
+		if err := decodeGremlin(raw, &ids[i]); err != nil {
+			return nil, err
+		}
+	}
+	return ids, nil
+}
+
+// upsertVertex inserts v, unless the vertex with GREMLIN_ID_PROPERTY gid is already there, and returns its id
+func (backend *GremlinBackend) upsertVertex(v schema.Vertex, gid string) (interface{}, error) {
+	props := v.Properties()
+	bindings := map[string]interface{}{
+		"lbl": v.Label(),
+		"gid": gid,
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "g.V().has(lbl, '%s', gid).fold().coalesce(unfold(), addV(lbl).property('%s', gid)", GREMLIN_ID_PROPERTY, GREMLIN_ID_PROPERTY)
+	for _, k := range sortedKeys(props) {
+		binding := "p_" + k
+		bindings[binding] = props[k]
+		fmt.Fprintf(&sb, ".property('%s', %s)", k, binding)
+	}
+	sb.WriteString(").id()")
+
+	data, err := backend.submit(sb.String(), bindings)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) != 1 {
+		return nil, fmt.Errorf("Expected 1 id back from gremlin but got %d", len(data))
+	}
+
+	var id interface{}
+	if err := decodeGremlin(data[0], &id); err != nil {
+		return nil, err
+	}
+	return id, nil
+}
+
+// flushVBulk inserts vertices in one request, or one at a time (skipping those already there) if this is a retry
+func (backend *GremlinBackend) flushVBulk(label string, vertices []schema.Vertex, gids []string, retrying bool) error {
+	if !retrying {
+		ids, err := backend.addVertices(label, vertices, gids)
+		if err != nil {
+			return fmt.Errorf("Error inserting %q vertices: %v", label, err)
+		}
+		for i, v := range vertices {
+			v.SetBackendMeta(GremlinID{ids[i]})
+		}
+		return nil
+	}
+
+	for i, v := range vertices {
+		id, err := backend.upsertVertex(v, gids[i])
+		if err != nil {
+			return fmt.Errorf("Error inserting %q vertices: %v", label, err)
+		}
+		v.SetBackendMeta(GremlinID{id})
+	}
+	return nil
+}
+
+func (backend *GremlinBackend) AddVStream(vertices chan schema.Vertex, progressCb func([]schema.Vertex)) *VertexStream {
+	stream := &VertexStream{}
+	for i := 0; i < BULK_WORKERS; i++ {
+		stream.Go(func() error {
+			vByLabel := map[string][]schema.Vertex{}
+			flush := func(label string) error {
+				stuff := vByLabel[label]
+				if len(stuff) == 0 {
+					return nil
+				}
+				gids := newUUIDs(len(stuff))
+				err := retry(func(attempt int) error {
+					return backend.flushVBulk(label, stuff, gids, attempt > 0)
+				})
+				if err != nil {
+					return err
+				}
+				progressCb(stuff)
+				vByLabel[label] = nil
+				return nil
+			}
+
+			for v := range vertices {
+				label := v.Label()
+				vByLabel[label] = append(vByLabel[label], v)
+				if len(vByLabel[label]) >= VERTEX_BATCH_SIZE {
+					if err := flush(label); err != nil {
+						Drain(vertices)
+						return err
+					}
+				}
+			}
+
+			for label := range vByLabel {
+				if err := flush(label); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	}
+
+	return stream
+}
+
+// flushEBulk inserts edges in one request. Edges whose GREMLIN_ID_PROPERTY (from gids) is already on an edge out of
+// their source are skipped, so batches can be retried.
+func (backend *GremlinBackend) flushEBulk(label string, edges []schema.Edge, gids []string) error {
+	bindings := map[string]interface{}{
+		"lbl": label,
+	}
+
+	// chain one addE per edge onto a single traversal so the whole batch is one round trip
+	var sb strings.Builder
+	sb.WriteString("g")
+	for i, edge := range edges {
+		bindings[fmt.Sprintf("s%d", i)] = edge.Source.GetBackendMeta().(GremlinID).ID
+		bindings[fmt.Sprintf("t%d", i)] = edge.Target.GetBackendMeta().(GremlinID).ID
+		bindings[fmt.Sprintf("e%d", i)] = gids[i]
+		fmt.Fprintf(&sb, ".V(s%d).coalesce(outE(lbl).has('%s', e%d), addE(lbl).to(__.V(t%d)).property('%s', e%d)", i, GREMLIN_ID_PROPERTY, i, i, GREMLIN_ID_PROPERTY, i)
+
+		for _, k := range sortedKeys(edge.Properties) {
+			binding := fmt.Sprintf("p%d_%s", i, k)
+			bindings[binding] = edge.Properties[k]
+			fmt.Fprintf(&sb, ".property('%s', %s)", k, binding)
+		}
+		sb.WriteString(")")
+	}
+	sb.WriteString(".count()")
+
+	_, err := backend.submit(sb.String(), bindings)
+	if err != nil {
+		return fmt.Errorf("Error inserting %q edges: %v", label, err)
+	}
+	return nil
+}
+
+func (backend *GremlinBackend) AddEBulk(allEdges []schema.Edge, progressCb func([]schema.Edge)) error {
+	logrus.Infof("Adding %d edges", len(allEdges))
+
+	work := make(chan schema.Edge)
+
+	var wg sync.WaitGroup
+	var errs firstError
+	for i := 0; i < BULK_WORKERS; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			eByLabel := map[string][]schema.Edge{}
+			flush := func(label string) error {
+				stuff := eByLabel[label]
+				if len(stuff) == 0 {
+					return nil
+				}
+				gids := newUUIDs(len(stuff))
+				err := retry(func(int) error {
+					return backend.flushEBulk(label, stuff, gids)
+				})
+				if err != nil {
+					return err
+				}
+				progressCb(stuff)
+				eByLabel[label] = nil
+				return nil
+			}
+
+			for e := range work {
+				eByLabel[e.Label] = append(eByLabel[e.Label], e)
+				if len(eByLabel[e.Label]) >= GREMLIN_EDGE_BATCH_SIZE {
+					if err := flush(e.Label); err != nil {
+						errs.set(err)
+						for range work {
+						}
+						return
+					}
+				}
+			}
+
+			for label := range eByLabel {
+				if err := flush(label); err != nil {
+					errs.set(err)
+					return
+				}
+			}
+		}()
+	}
+
+	for _, e := range allEdges {
+		work <- e
+	}
+
+	close(work)
+	wg.Wait()
+	return errs.get()
+}
+
+// gremlinVertex converts a vertex's valueMap back into the schema type for label
+func gremlinVertex(label string, id interface{}, valueMap map[string][]json.RawMessage) (schema.Vertex, error) {
+	v, err := schema.NewVertex(label)
+	if err != nil {
+		return nil, err
+	}
+
+	// vertex properties come back as lists, since they could have more than one value
+	props := map[string]json.RawMessage{}
+	for k, vals := range valueMap {
+		if len(vals) > 0 {
+			props[k] = vals[0]
+		}
+	}
+
+	buf, err := json.Marshal(props)
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeGremlin(buf, v); err != nil {
+		return nil, err
+	}
+
+	v.SetBackendMeta(GremlinID{id})
+	return v, nil
+}
+
+// gremlinEdgeProperties decodes an edge's valueMap, leaving out GREMLIN_ID_PROPERTY
+func gremlinEdgeProperties(valueMap json.RawMessage) (map[string]interface{}, error) {
+	props := map[string]interface{}{}
+	if err := json.Unmarshal(valueMap, &props); err != nil {
+		return nil, err
+	}
+	delete(props, GREMLIN_ID_PROPERTY)
+	return props, nil
+}
+
+// traverse follows edges with the given label from v, in direction "out" or "in"
+func (backend *GremlinBackend) traverse(v schema.Vertex, label string, direction string) ([]schema.Edge, error) {
+	if err := checkEdgeLabel(label); err != nil {
+		return nil, err
+	}
+
+	id, ok := v.GetBackendMeta().(GremlinID)
+	if !ok {
+		return nil, fmt.Errorf("Vertex %v is not in this backend", v)
+	}
+
+	other := "inV()"
+	if direction == "in" {
+		other = "outV()"
+	}
+
+	data, err := backend.submit(
+		fmt.Sprintf("g.V(vid).%sE(lbl).project('props', 'id', 'label', 'other').by(valueMap()).by(%s.id()).by(%s.label()).by(%s.valueMap())", direction, other, other, other),
+		map[string]interface{}{
+			"vid": id.ID,
+			"lbl": label,
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	edges := []schema.Edge{}
+	for _, raw := range data {
+		var row struct {
+			Props json.RawMessage              `json:"props"`
+			ID    interface{}                  `json:"id"`
+			Label string                       `json:"label"`
+			Other map[string][]json.RawMessage `json:"other"`
+		}
+		if err := decodeGremlin(raw, &row); err != nil {
+			return nil, err
+		}
+
+		other, err := gremlinVertex(row.Label, row.ID, row.Other)
+		if err != nil {
+			return nil, err
+		}
+		props, err := gremlinEdgeProperties(row.Props)
+		if err != nil {
+			return nil, err
+		}
+
+		edge := schema.Edge{Source: v, Label: label, Target: other, Properties: props}
+		if direction == "in" {
+			edge.Source, edge.Target = other, v
+		}
+		edges = append(edges, edge)
+	}
+
+	return edges, nil
+}
+
+func (backend *GremlinBackend) OutEdges(v schema.Vertex, label string) ([]schema.Edge, error) {
+	return backend.traverse(v, label, "out")
+}
+
+func (backend *GremlinBackend) InEdges(v schema.Vertex, label string) ([]schema.Edge, error) {
+	return backend.traverse(v, label, "in")
+}
+
+func (backend *GremlinBackend) ScanVertices(cb func(schema.Vertex) error) error {
+	for _, label := range schema.VertexLabels {
+		zero, err := schema.NewVertex(label)
+		if err != nil {
+			return err
+		}
+
+		// TODO: this reads every vertex with the label in one response
+		data, err := backend.submit("g.V().hasLabel(lbl)"+gremlinProjection(sortedKeys(zero.Properties())), map[string]interface{}{
+			"lbl": label,
+		})
+		if err != nil {
+			return err
+		}
+
+		vertices, err := readVertices(data, func() schema.Vertex {
+			v, _ := schema.NewVertex(label)
+			return v
+		})
+		if err != nil {
+			return err
+		}
+		for _, v := range vertices {
+			if err := cb(v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// gremlinStub makes an empty vertex which only has its backend meta set
+func gremlinStub(label string, id interface{}) (schema.Vertex, error) {
+	v, err := schema.NewVertex(label)
+	if err != nil {
+		return nil, err
+	}
+	v.SetBackendMeta(GremlinID{id})
+	return v, nil
+}
+
+func (backend *GremlinBackend) ScanEdges(cb func(schema.Edge) error) error {
+	for _, def := range schema.EdgeDefinitions {
+		data, err := backend.submit(
+			"g.E().hasLabel(lbl).project('props', 'src', 'srcLabel', 'dst', 'dstLabel').by(valueMap()).by(outV().id()).by(outV().label()).by(inV().id()).by(inV().label())",
+			map[string]interface{}{
+				"lbl": def.Label,
+			},
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, raw := range data {
+			var row struct {
+				Props    json.RawMessage `json:"props"`
+				Src      interface{}     `json:"src"`
+				SrcLabel string          `json:"srcLabel"`
+				Dst      interface{}     `json:"dst"`
+				DstLabel string          `json:"dstLabel"`
+			}
+			if err := decodeGremlin(raw, &row); err != nil {
+				return err
+			}
+
+			edge := schema.Edge{Label: def.Label}
+			if edge.Source, err = gremlinStub(row.SrcLabel, row.Src); err != nil {
+				return err
+			}
+			if edge.Target, err = gremlinStub(row.DstLabel, row.Dst); err != nil {
+				return err
+			}
+			if edge.Properties, err = gremlinEdgeProperties(row.Props); err != nil {
+				return err
+			}
+
+			if err := cb(edge); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package backend_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/gorilla/websocket"
+
+	gbackend "github.com/kallsyms/go-graph/backend"
+	"github.com/kallsyms/go-graph/coordination"
+)
+
+// fakeGremlinServer answers every request with no results, except that the first connection is dropped as soon as a
+// request arrives on it
+func fakeGremlinServer(t *testing.T) *httptest.Server {
+	var conns int32
+	upgrader := websocket.Upgrader{}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer ws.Close()
+		first := atomic.AddInt32(&conns, 1) == 1
+
+		for {
+			_, msg, err := ws.ReadMessage()
+			if err != nil || first {
+				return
+			}
+
+			// skip the mime type prefix
+			var req struct {
+				RequestID string `json:"requestId"`
+			}
+			if err := json.Unmarshal(msg[1+int(msg[0]):], &req); err != nil {
+				t.Error(err)
+				return
+			}
+
+			resp := map[string]interface{}{
+				"requestId": req.RequestID,
+				"status":    map[string]interface{}{"code": 204},
+				"result":    map[string]interface{}{"data": nil},
+			}
+			if err := ws.WriteJSON(resp); err != nil {
+				return
+			}
+		}
+	}))
+}
+
+func TestGremlinReconnect(t *testing.T) {
+	server := fakeGremlinServer(t)
+	defer server.Close()
+
+	b, err := gbackend.NewGremlinBackend(strings.Replace(server.URL, "http://", "ws://", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tup := coordination.PackageTuple{Name: "example.com/p", Version: "v1.0.0"}
+	if _, _, err := b.GetPackage(tup); err == nil {
+		t.Fatalf("GetPackage on a dropped connection didn't fail")
+	}
+
+	// the next request gets a new connection
+	if _, found, err := b.GetPackage(tup); err != nil || found {
+		t.Fatalf("GetPackage after reconnecting = %v, %v", found, err)
+	}
+}
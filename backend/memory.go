@@ -0,0 +1,507 @@
+package backend
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kallsyms/go-graph/coordination"
+	"github.com/kallsyms/go-graph/schema"
+)
+
+// MemoryID is the backend meta attached to every vertex stored in a MemoryBackend.
+type MemoryID uint64
+
+// MemoryBackend keeps the whole graph in process memory.
+// Useful for tests and small one-off analyses where spinning up a DB isn't worth it.
+type MemoryBackend struct {
+	mtx      sync.RWMutex
+	nextID   MemoryID
+	vertices map[MemoryID]schema.Vertex
+	// adjacency lists, keyed by vertex then by edge label
+	out      map[MemoryID]map[string][]schema.Edge
+	in       map[MemoryID]map[string][]schema.Edge
+	packages map[coordination.PackageTuple]*schema.Package
+}
+
+func init() {
+	// mainly useful for trying out an ingest, since everything is gone when the process exits
+	Register("mem", func(*url.URL) (Backend, error) {
+		return NewMemoryBackend(), nil
+	})
+}
+
+func NewMemoryBackend() *MemoryBackend {
+	backend := &MemoryBackend{}
+	backend.reset()
+	return backend
+}
+
+func (backend *MemoryBackend) reset() {
+	backend.nextID = 0
+	backend.vertices = map[MemoryID]schema.Vertex{}
+	backend.out = map[MemoryID]map[string][]schema.Edge{}
+	backend.in = map[MemoryID]map[string][]schema.Edge{}
+	backend.packages = map[coordination.PackageTuple]*schema.Package{}
+}
+
+func memoryID(v schema.Vertex) (MemoryID, bool) {
+	if v == nil {
+		return 0, false
+	}
+	id, ok := v.GetBackendMeta().(MemoryID)
+	return id, ok
+}
+
+// must be called with mtx held
+func (backend *MemoryBackend) addVertex(v schema.Vertex) {
+	backend.nextID++
+	v.SetBackendMeta(backend.nextID)
+	backend.vertices[backend.nextID] = v
+}
+
+// must be called with mtx held
+func (backend *MemoryBackend) addEdge(edge schema.Edge) error {
+	src, ok := memoryID(edge.Source)
+	if !ok {
+		return fmt.Errorf("Edge %q source %v was never added", edge.Label, edge.Source)
+	}
+	dst, ok := memoryID(edge.Target)
+	if !ok {
+		return fmt.Errorf("Edge %q target %v was never added", edge.Label, edge.Target)
+	}
+
+	if backend.out[src] == nil {
+		backend.out[src] = map[string][]schema.Edge{}
+	}
+	backend.out[src][edge.Label] = append(backend.out[src][edge.Label], edge)
+
+	if backend.in[dst] == nil {
+		backend.in[dst] = map[string][]schema.Edge{}
+	}
+	backend.in[dst][edge.Label] = append(backend.in[dst][edge.Label], edge)
+
+	return nil
+}
+
+func (backend *MemoryBackend) DropAll() error {
+	backend.mtx.Lock()
+	defer backend.mtx.Unlock()
+
+	backend.reset()
+	return nil
+}
+
+func (backend *MemoryBackend) CreateSchema() error {
+	return nil
+}
+
+func (backend *MemoryBackend) GetPackages() ([]*schema.Package, error) {
+	backend.mtx.RLock()
+	defer backend.mtx.RUnlock()
+
+	pkgs := []*schema.Package{}
+	for _, pkg := range backend.packages {
+		pkgs = append(pkgs, pkg)
+	}
+
+	return pkgs, nil
+}
+
+func (backend *MemoryBackend) GetPackage(tup coordination.PackageTuple) (*schema.Package, bool, error) {
+	backend.mtx.RLock()
+	defer backend.mtx.RUnlock()
+
+	pkg, found := backend.packages[tup]
+	return pkg, found, nil
+}
+
+func (backend *MemoryBackend) CreatePackage(tup coordination.PackageTuple) (*schema.Package, error) {
+	backend.mtx.Lock()
+	defer backend.mtx.Unlock()
+
+	if _, found := backend.packages[tup]; found {
+		return nil, fmt.Errorf("Package %v already exists", tup)
+	}
+
+	pkg := &schema.Package{
+		SourceURL: tup.Name,
+		Version:   tup.Version,
+	}
+	backend.addVertex(pkg)
+	backend.packages[tup] = pkg
+
+	return pkg, nil
+}
+
+func (backend *MemoryBackend) PackageFunctions(pkg *schema.Package) (map[string]*schema.Function, error) {
+	id, ok := memoryID(pkg)
+	if !ok {
+		return nil, fmt.Errorf("Package %v is not in this backend", pkg)
+	}
+
+	backend.mtx.RLock()
+	defer backend.mtx.RUnlock()
+
+	functions := map[string]*schema.Function{}
+	for _, edge := range backend.out[id]["Functions"] {
+		if f, ok := edge.Target.(*schema.Function); ok {
+			if err := addFunction(functions, f); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return functions, nil
+}
+
+func (backend *MemoryBackend) PackageTypes(pkg *schema.Package) (map[string]*schema.Type, error) {
+	id, ok := memoryID(pkg)
+	if !ok {
+		return nil, fmt.Errorf("Package %v is not in this backend", pkg)
+	}
+
+	backend.mtx.RLock()
+	defer backend.mtx.RUnlock()
+
+	types := map[string]*schema.Type{}
+	for _, edge := range backend.out[id]["Types"] {
+		if t, ok := edge.Target.(*schema.Type); ok {
+			types[t.Symbol] = t
+		}
+	}
+
+	return types, nil
+}
+
+func (backend *MemoryBackend) PackageFields(pkg *schema.Package) (map[string]*schema.Field, error) {
+	id, ok := memoryID(pkg)
+	if !ok {
+		return nil, fmt.Errorf("Package %v is not in this backend", pkg)
+	}
+
+	backend.mtx.RLock()
+	defer backend.mtx.RUnlock()
+
+	fields := map[string]*schema.Field{}
+	for _, typeEdge := range backend.out[id]["Types"] {
+		t, ok := typeEdge.Target.(*schema.Type)
+		if !ok {
+			continue
+		}
+		typeID, _ := memoryID(t)
+		for _, edge := range backend.out[typeID]["Fields"] {
+			if f, ok := edge.Target.(*schema.Field); ok {
+				fields[t.Symbol+"."+f.Name] = f
+			}
+		}
+	}
+
+	return fields, nil
+}
+
+func (backend *MemoryBackend) PackageConstants(pkg *schema.Package) (map[string]*schema.Constant, error) {
+	id, ok := memoryID(pkg)
+	if !ok {
+		return nil, fmt.Errorf("Package %v is not in this backend", pkg)
+	}
+
+	backend.mtx.RLock()
+	defer backend.mtx.RUnlock()
+
+	constants := map[string]*schema.Constant{}
+	for _, edge := range backend.out[id]["Constants"] {
+		if c, ok := edge.Target.(*schema.Constant); ok {
+			constants[c.Symbol] = c
+		}
+	}
+
+	return constants, nil
+}
+
+func (backend *MemoryBackend) AddVStream(vertices chan schema.Vertex, progressCb func([]schema.Vertex)) *VertexStream {
+	stream := &VertexStream{}
+	stream.Go(func() error {
+		batch := []schema.Vertex{}
+		for v := range vertices {
+			backend.mtx.Lock()
+			backend.addVertex(v)
+			backend.mtx.Unlock()
+
+			batch = append(batch, v)
+			if len(batch) >= VERTEX_BATCH_SIZE {
+				progressCb(batch)
+				batch = []schema.Vertex{}
+			}
+		}
+
+		if len(batch) > 0 {
+			progressCb(batch)
+		}
+		return nil
+	})
+
+	return stream
+}
+
+func (backend *MemoryBackend) AddEBulk(edges []schema.Edge, progressCb func([]schema.Edge)) error {
+	logrus.Infof("Adding %d edges", len(edges))
+
+	for start := 0; start < len(edges); start += EDGE_BATCH_SIZE {
+		end := start + EDGE_BATCH_SIZE
+		if end > len(edges) {
+			end = len(edges)
+		}
+		batch := edges[start:end]
+
+		backend.mtx.Lock()
+		for _, edge := range batch {
+			if err := backend.addEdge(edge); err != nil {
+				backend.mtx.Unlock()
+				return err
+			}
+		}
+		backend.mtx.Unlock()
+
+		progressCb(batch)
+	}
+	return nil
+}
+
+// OutEdges returns all edges with the given label leaving v.
+func (backend *MemoryBackend) OutEdges(v schema.Vertex, label string) ([]schema.Edge, error) {
+	if err := checkEdgeLabel(label); err != nil {
+		return nil, err
+	}
+	return backend.outEdges(v, label), nil
+}
+
+// InEdges returns all edges with the given label arriving at v.
+func (backend *MemoryBackend) InEdges(v schema.Vertex, label string) ([]schema.Edge, error) {
+	if err := checkEdgeLabel(label); err != nil {
+		return nil, err
+	}
+	return backend.inEdges(v, label), nil
+}
+
+// unlike OutEdges and InEdges, an empty label matches every edge (in label order), for Traversal steps without labels
+func (backend *MemoryBackend) outEdges(v schema.Vertex, label string) []schema.Edge {
+	backend.mtx.RLock()
+	defer backend.mtx.RUnlock()
+
+	id, _ := memoryID(v)
+	return collectEdges(backend.out[id], label)
+}
+
+func (backend *MemoryBackend) inEdges(v schema.Vertex, label string) []schema.Edge {
+	backend.mtx.RLock()
+	defer backend.mtx.RUnlock()
+
+	id, _ := memoryID(v)
+	return collectEdges(backend.in[id], label)
+}
+
+func collectEdges(byLabel map[string][]schema.Edge, label string) []schema.Edge {
+	if label != "" {
+		return append([]schema.Edge{}, byLabel[label]...)
+	}
+
+	labels := make([]string, 0, len(byLabel))
+	for l := range byLabel {
+		labels = append(labels, l)
+	}
+	sort.Strings(labels)
+
+	edges := []schema.Edge{}
+	for _, l := range labels {
+		edges = append(edges, byLabel[l]...)
+	}
+	return edges
+}
+
+// Traversal is a set of vertices which can be walked along edges and filtered, roughly like a gremlin traversal.
+// Each step returns a new Traversal, so partial traversals can be reused.
+//
+//	calls := backend.V("package").Has("SourceURL", "fmt").
+//		Out("Functions").Has("Name", "Println").
+//		In("Callee").Out("CallSiteStatement").ToList()
+type Traversal struct {
+	backend  *MemoryBackend
+	vertices []schema.Vertex
+}
+
+// V starts a traversal at every vertex with one of the given labels, or every vertex if no labels are given.
+func (backend *MemoryBackend) V(labels ...string) *Traversal {
+	backend.mtx.RLock()
+	defer backend.mtx.RUnlock()
+
+	vertices := []schema.Vertex{}
+	// iterate in insertion order so results are deterministic
+	for id := MemoryID(1); id <= backend.nextID; id++ {
+		if v, ok := backend.vertices[id]; ok && hasLabel(v, labels) {
+			vertices = append(vertices, v)
+		}
+	}
+
+	return &Traversal{backend, vertices}
+}
+
+// From starts a traversal at the given vertices.
+func (backend *MemoryBackend) From(vertices ...schema.Vertex) *Traversal {
+	return &Traversal{backend, vertices}
+}
+
+func hasLabel(v schema.Vertex, labels []string) bool {
+	if len(labels) == 0 {
+		return true
+	}
+	for _, label := range labels {
+		if v.Label() == label {
+			return true
+		}
+	}
+	return false
+}
+
+// Where keeps only vertices for which pred returns true.
+func (t *Traversal) Where(pred func(schema.Vertex) bool) *Traversal {
+	vertices := []schema.Vertex{}
+	for _, v := range t.vertices {
+		if pred(v) {
+			vertices = append(vertices, v)
+		}
+	}
+	return &Traversal{t.backend, vertices}
+}
+
+// Has keeps only vertices whose property key equals value.
+func (t *Traversal) Has(key string, value interface{}) *Traversal {
+	return t.Where(func(v schema.Vertex) bool {
+		prop, ok := v.Properties()[key]
+		return ok && prop == value
+	})
+}
+
+// HasLabel keeps only vertices with one of the given labels.
+func (t *Traversal) HasLabel(labels ...string) *Traversal {
+	return t.Where(func(v schema.Vertex) bool {
+		return hasLabel(v, labels)
+	})
+}
+
+// Out follows outbound edges with one of the given labels (or any label if none are given).
+func (t *Traversal) Out(labels ...string) *Traversal {
+	return t.OutWhere(nil, labels...)
+}
+
+// In follows inbound edges with one of the given labels (or any label if none are given).
+func (t *Traversal) In(labels ...string) *Traversal {
+	return t.InWhere(nil, labels...)
+}
+
+// OutWhere is Out, but only follows edges for which pred returns true.
+// A nil pred follows every edge.
+func (t *Traversal) OutWhere(pred func(schema.Edge) bool, labels ...string) *Traversal {
+	return t.step(pred, labels, t.backend.outEdges, func(e schema.Edge) schema.Vertex { return e.Target })
+}
+
+// InWhere is In, but only follows edges for which pred returns true.
+// A nil pred follows every edge.
+func (t *Traversal) InWhere(pred func(schema.Edge) bool, labels ...string) *Traversal {
+	return t.step(pred, labels, t.backend.inEdges, func(e schema.Edge) schema.Vertex { return e.Source })
+}
+
+func (t *Traversal) step(pred func(schema.Edge) bool, labels []string, edgesOf func(schema.Vertex, string) []schema.Edge, other func(schema.Edge) schema.Vertex) *Traversal {
+	if len(labels) == 0 {
+		labels = []string{""}
+	}
+
+	vertices := []schema.Vertex{}
+	for _, v := range t.vertices {
+		for _, label := range labels {
+			for _, edge := range edgesOf(v, label) {
+				if pred == nil || pred(edge) {
+					vertices = append(vertices, other(edge))
+				}
+			}
+		}
+	}
+	return &Traversal{t.backend, vertices}
+}
+
+// Dedup removes duplicate vertices, keeping the first occurrence of each.
+func (t *Traversal) Dedup() *Traversal {
+	seen := map[schema.Vertex]bool{}
+	return t.Where(func(v schema.Vertex) bool {
+		if seen[v] {
+			return false
+		}
+		seen[v] = true
+		return true
+	})
+}
+
+func (t *Traversal) ToList() []schema.Vertex {
+	return t.vertices
+}
+
+func (t *Traversal) Count() int {
+	return len(t.vertices)
+}
+
+// Paths returns every path starting at start which follows edges with the given label, for which follow returns
+// true (or every edge if follow is nil), up to maxDepth edges long.
+// Paths end when there are no more edges to follow, when maxDepth is reached, or when a vertex would be revisited.
+// This is the equivalent of the README's "all paths through a function" query.
+func (backend *MemoryBackend) Paths(start schema.Vertex, label string, maxDepth int, follow func(schema.Edge) bool) [][]schema.Vertex {
+	paths := [][]schema.Vertex{}
+	onPath := map[schema.Vertex]bool{}
+
+	var walk func(path []schema.Vertex)
+	walk = func(path []schema.Vertex) {
+		cur := path[len(path)-1]
+		extended := false
+
+		if len(path)-1 < maxDepth {
+			onPath[cur] = true
+			for _, edge := range backend.outEdges(cur, label) {
+				if onPath[edge.Target] || (follow != nil && !follow(edge)) {
+					continue
+				}
+				extended = true
+				walk(append(path[:len(path):len(path)], edge.Target))
+			}
+			delete(onPath, cur)
+		}
+
+		if !extended {
+			paths = append(paths, path)
+		}
+	}
+	walk([]schema.Vertex{start})
+
+	return paths
+}
+
+func (backend *MemoryBackend) ScanVertices(cb func(schema.Vertex) error) error {
+	for _, v := range backend.V().ToList() {
+		if err := cb(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (backend *MemoryBackend) ScanEdges(cb func(schema.Edge) error) error {
+	for _, v := range backend.V().ToList() {
+		for _, edge := range backend.outEdges(v, "") {
+			if err := cb(edge); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package backend_test
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	gbackend "github.com/kallsyms/go-graph/backend"
+	"github.com/kallsyms/go-graph/backend/backendtest"
+	"github.com/kallsyms/go-graph/coordination"
+	"github.com/kallsyms/go-graph/schema"
+)
+
+func TestMemoryConformance(t *testing.T) {
+	backendtest.Run(t, func(t *testing.T) gbackend.Backend {
+		return gbackend.NewMemoryBackend()
+	})
+}
+
+// memoryGraph builds a package with functions Run and helper, where Run calls helper from inside a loop:
+// s0 -> s1 -> s2 -> s1 (back edge), s2 -> s3
+func memoryGraph(t *testing.T) (*gbackend.MemoryBackend, []*schema.Statement) {
+	backend := gbackend.NewMemoryBackend()
+	pkg, err := backend.CreatePackage(coordination.PackageTuple{Name: "example.com/p", Version: "v1.0.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := &schema.Function{Name: "Run", Symbol: "example.com/p.Run"}
+	helper := &schema.Function{Name: "helper", Symbol: "example.com/p.helper"}
+	call := &schema.FunctionCall{Kind: "call"}
+	stmts := []*schema.Statement{}
+	for i := 0; i < 4; i++ {
+		stmts = append(stmts, &schema.Statement{Offset: i, Text: fmt.Sprintf("s%d", i)})
+	}
+
+	vertices := make(chan schema.Vertex)
+	stream := backend.AddVStream(vertices, func([]schema.Vertex) {})
+	for _, v := range []schema.Vertex{run, helper, call, stmts[0], stmts[1], stmts[2], stmts[3]} {
+		vertices <- v
+	}
+	close(vertices)
+	if err := stream.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	next := func(from, to int, back bool) schema.Edge {
+		return schema.Edge{Source: stmts[from], Label: "Next", Target: stmts[to], Properties: map[string]interface{}{"isBackEdge": back}}
+	}
+	edges := []schema.Edge{
+		{Source: pkg, Label: "Functions", Target: run},
+		{Source: pkg, Label: "Functions", Target: helper},
+		{Source: run, Label: "Calls", Target: call},
+		{Source: call, Label: "Callee", Target: helper},
+		{Source: call, Label: "CallSiteStatement", Target: stmts[2]},
+		next(0, 1, false),
+		next(1, 2, false),
+		next(2, 1, true),
+		next(2, 3, false),
+	}
+	for _, s := range stmts {
+		edges = append(edges, schema.Edge{Source: run, Label: "Statement", Target: s})
+	}
+	if err := backend.AddEBulk(edges, func([]schema.Edge) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	return backend, stmts
+}
+
+// texts returns the Text (or Name, for functions) of each vertex
+func texts(vertices []schema.Vertex) []string {
+	names := []string{}
+	for _, v := range vertices {
+		switch v := v.(type) {
+		case *schema.Statement:
+			names = append(names, v.Text)
+		case *schema.Function:
+			names = append(names, v.Name)
+		default:
+			names = append(names, v.Label())
+		}
+	}
+	return names
+}
+
+func TestMemoryTraversal(t *testing.T) {
+	backend, _ := memoryGraph(t)
+
+	tests := []struct {
+		name string
+		t    *gbackend.Traversal
+		want []string
+	}{
+		{"V", backend.V("function"), []string{"Run", "helper"}},
+		{"V without labels", backend.V().HasLabel("function", "functioncall"), []string{"Run", "helper", "functioncall"}},
+		{"Has", backend.V("function").Has("Name", "helper"), []string{"helper"}},
+		{"Has wrong type", backend.V("statement").Has("Offset", "1"), []string{}},
+		{"Out", backend.V("package").Out("Functions").Has("Name", "Run").Out("Calls").Out("Callee"), []string{"helper"}},
+		{"In", backend.V("function").Has("Name", "helper").In("Callee").Out("CallSiteStatement"), []string{"s2"}},
+		{"Out several labels", backend.V("statement").Has("Text", "s2").Out("Next", "Bogus"), []string{"s1", "s3"}},
+		{"Out every label", backend.V("function").Has("Name", "Run").Out().HasLabel("functioncall"), []string{"functioncall"}},
+		{
+			"OutWhere",
+			backend.V("statement").OutWhere(func(e schema.Edge) bool { return e.Properties["isBackEdge"] == true }, "Next"),
+			[]string{"s1"},
+		},
+		{"Dedup", backend.V("statement").Out("Next").Dedup(), []string{"s1", "s2", "s3"}},
+	}
+
+	for _, tt := range tests {
+		if got := texts(tt.t.ToList()); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if n := backend.V("statement").Out("Next").Count(); n != 4 {
+		t.Errorf("Count of Next targets = %d, want 4 (with duplicates)", n)
+	}
+}
+
+func TestMemoryPaths(t *testing.T) {
+	backend, stmts := memoryGraph(t)
+
+	got := [][]string{}
+	for _, path := range backend.Paths(stmts[0], "Next", 10, nil) {
+		got = append(got, texts(path))
+	}
+	// the back edge s2 -> s1 would revisit s1, so it isn't followed
+	want := [][]string{{"s0", "s1", "s2", "s3"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Paths = %v, want %v", got, want)
+	}
+
+	got = [][]string{}
+	for _, path := range backend.Paths(stmts[0], "Next", 1, nil) {
+		got = append(got, texts(path))
+	}
+	if want := [][]string{{"s0", "s1"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Paths with maxDepth 1 = %v, want %v", got, want)
+	}
+
+	// with s2 -> s3 filtered out as well, s2 is a dead end
+	got = [][]string{}
+	notS3 := func(e schema.Edge) bool { return e.Target != stmts[3] }
+	for _, path := range backend.Paths(stmts[0], "Next", 10, notS3) {
+		got = append(got, texts(path))
+	}
+	if want := [][]string{{"s0", "s1", "s2"}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Paths not following edges to s3 = %v, want %v", got, want)
+	}
+}
+
+func TestMemoryScanEdgesOrder(t *testing.T) {
+	scan := func() []string {
+		backend, _ := memoryGraph(t)
+		edges := []string{}
+		err := backend.ScanEdges(func(e schema.Edge) error {
+			edges = append(edges, fmt.Sprintf("%v %s %v", e.Source.GetBackendMeta(), e.Label, e.Target.GetBackendMeta()))
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return edges
+	}
+
+	first := scan()
+	for i := 0; i < 10; i++ {
+		if again := scan(); !reflect.DeepEqual(first, again) {
+			t.Fatalf("ScanEdges order changed between runs:\n%v\n%v", first, again)
+		}
+	}
+}
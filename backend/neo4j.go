@@ -0,0 +1,652 @@
+package backend
+
+import (
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/kallsyms/go-graph/coordination"
+	"github.com/kallsyms/go-graph/schema"
+)
+
+// Neo4jID is the backend meta attached to vertices written by a Neo4jCSVBackend.
+// It's the value of the node's :ID column, which is unique across all node files.
+type Neo4jID string
+
+type neo4jCSVFile struct {
+	path string
+	fh   *os.File
+	w    *csv.Writer
+	keys []string
+}
+
+// Neo4jCSVBackend doesn't talk to a DB at all, it just writes vertices and edges as CSV files in the format
+// `neo4j-admin database import` expects: one node file per vertex label under nodes/, and one relationship file per
+// edge label under relationships/.
+// An import.args file listing them all is written alongside, so the whole directory can be imported with
+//
+//	neo4j-admin database import full @/path/to/dir/import.args
+//
+// Files from earlier ingests into the same directory are appended to, and their packages, functions, types, fields
+// and constants are read back (and kept in memory) so that dependencies are only ever written once.
+//
+// Node ids are derived from the vertex's label and properties, so the same code gets the same ids in every ingest.
+type Neo4jCSVBackend struct {
+	dir string
+
+	mtx sync.Mutex
+	// how many vertices have been written with each content derived id, so that vertices with the same content (e.g.
+	// two err variables) still get different ids
+	written   map[string]int
+	nodes     map[string]*neo4jCSVFile
+	rels      map[string]*neo4jCSVFile
+	packages  map[coordination.PackageTuple]*schema.Package
+	functions map[*schema.Package][]*schema.Function
+	types     map[*schema.Package]map[string]*schema.Type
+	constants map[*schema.Package]map[string]*schema.Constant
+	fields    map[*schema.Type][]*schema.Field
+}
+
+func init() {
+	Register("neo4j-csv", func(u *url.URL) (Backend, error) {
+		return NewNeo4jCSVBackend(urlPath(u))
+	})
+}
+
+func NewNeo4jCSVBackend(dir string) (*Neo4jCSVBackend, error) {
+	// import.args needs absolute paths so it works from wherever neo4j-admin is run
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	backend := &Neo4jCSVBackend{
+		dir: dir,
+	}
+
+	if err := backend.CreateSchema(); err != nil {
+		return nil, err
+	}
+
+	return backend, nil
+}
+
+// neo4jType returns the header type suffix for a property with the same type as v
+func neo4jType(v interface{}) string {
+	switch v.(type) {
+	case int, int64:
+		return ":long"
+	case float64:
+		return ":double"
+	case bool:
+		return ":boolean"
+	default:
+		return ""
+	}
+}
+
+// readNeo4jCSV calls cb with every record (but not the header) of the CSV file at path, if it exists.
+// It returns the header, which is nil if the file doesn't exist or is empty.
+func readNeo4jCSV(path string, cb func(record []string) error) ([]string, error) {
+	fh, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	r := csv.NewReader(fh)
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("Error reading %s: %v", path, err)
+	}
+
+	if cb == nil {
+		return header, nil
+	}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return header, nil
+		} else if err != nil {
+			return nil, fmt.Errorf("Error reading %s: %v", path, err)
+		}
+		if err := cb(record); err != nil {
+			return nil, fmt.Errorf("Error reading %s: %v", path, err)
+		}
+	}
+}
+
+// openNeo4jCSVFile opens the CSV file at path for appending, only writing the header if the file is new.
+// An existing file must have the same header, otherwise the new rows wouldn't line up with it.
+func openNeo4jCSVFile(path string, header []string, keys []string) (*neo4jCSVFile, error) {
+	existing, err := readNeo4jCSV(path, nil)
+	if err != nil {
+		return nil, err
+	}
+	if existing != nil && strings.Join(existing, ",") != strings.Join(header, ",") {
+		return nil, fmt.Errorf("%s was written with a different schema (header %q, expected %q), delete it to start over", path, existing, header)
+	}
+
+	fh, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	w := csv.NewWriter(fh)
+	if existing == nil {
+		if err := w.Write(header); err != nil {
+			fh.Close()
+			return nil, err
+		}
+		w.Flush()
+	}
+
+	return &neo4jCSVFile{path, fh, w, keys}, w.Error()
+}
+
+func (backend *Neo4jCSVBackend) closeAll() {
+	for _, f := range backend.nodes {
+		f.w.Flush()
+		f.fh.Close()
+	}
+	for _, f := range backend.rels {
+		f.w.Flush()
+		f.fh.Close()
+	}
+}
+
+func (backend *Neo4jCSVBackend) DropAll() error {
+	backend.mtx.Lock()
+	defer backend.mtx.Unlock()
+
+	backend.closeAll()
+	backend.nodes = nil
+	backend.rels = nil
+	backend.written = map[string]int{}
+	backend.packages = map[coordination.PackageTuple]*schema.Package{}
+	backend.functions = map[*schema.Package][]*schema.Function{}
+	backend.types = map[*schema.Package]map[string]*schema.Type{}
+	backend.constants = map[*schema.Package]map[string]*schema.Constant{}
+	backend.fields = map[*schema.Type][]*schema.Field{}
+
+	for _, sub := range []string{"nodes", "relationships", "import.args"} {
+		if err := os.RemoveAll(filepath.Join(backend.dir, sub)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateSchema creates any missing CSV files with just their header, and reads back what's already in the others.
+func (backend *Neo4jCSVBackend) CreateSchema() error {
+	backend.mtx.Lock()
+	defer backend.mtx.Unlock()
+
+	backend.closeAll()
+	backend.nodes = map[string]*neo4jCSVFile{}
+	backend.rels = map[string]*neo4jCSVFile{}
+	backend.written = map[string]int{}
+	backend.packages = map[coordination.PackageTuple]*schema.Package{}
+	backend.functions = map[*schema.Package][]*schema.Function{}
+	backend.types = map[*schema.Package]map[string]*schema.Type{}
+	backend.constants = map[*schema.Package]map[string]*schema.Constant{}
+	backend.fields = map[*schema.Type][]*schema.Field{}
+
+	for _, sub := range []string{"nodes", "relationships"} {
+		if err := os.MkdirAll(filepath.Join(backend.dir, sub), 0755); err != nil {
+			return err
+		}
+	}
+
+	// statement text often spans lines
+	args := []string{"--multiline-fields=true"}
+
+	for _, label := range schema.VertexLabels {
+		v, err := schema.NewVertex(label)
+		if err != nil {
+			// programming error
+			panic(err)
+		}
+
+		props := v.Properties()
+		keys := sortedKeys(props)
+		header := []string{"id:ID"}
+		for _, k := range keys {
+			header = append(header, k+neo4jType(props[k]))
+		}
+		header = append(header, ":LABEL")
+
+		path := filepath.Join(backend.dir, "nodes", label+".csv")
+		f, err := openNeo4jCSVFile(path, header, keys)
+		if err != nil {
+			return err
+		}
+		backend.nodes[label] = f
+		args = append(args, "--nodes="+path)
+	}
+
+	for _, def := range schema.EdgeDefinitions {
+		keys := sortedKeys(def.Properties)
+		header := []string{":START_ID", ":END_ID"}
+		for _, k := range keys {
+			header = append(header, k+neo4jType(def.Properties[k]))
+		}
+		header = append(header, ":TYPE")
+
+		path := filepath.Join(backend.dir, "relationships", def.Label+".csv")
+		f, err := openNeo4jCSVFile(path, header, keys)
+		if err != nil {
+			return err
+		}
+		backend.rels[def.Label] = f
+		args = append(args, "--relationships="+path)
+	}
+
+	if err := os.WriteFile(filepath.Join(backend.dir, "import.args"), []byte(strings.Join(args, "\n")+"\n"), 0644); err != nil {
+		return err
+	}
+
+	return backend.load()
+}
+
+// load reads back the vertices and edges earlier ingests wrote which later ones need: every node id, so new ids don't
+// clash with them, and packages along with their functions, types, fields and constants.
+// must be called with mtx held
+func (backend *Neo4jCSVBackend) load() error {
+	kept := map[string]bool{"package": true, "function": true, "type": true, "field": true, "constant": true}
+	vertices := map[Neo4jID]schema.Vertex{}
+
+	for _, label := range schema.VertexLabels {
+		f := backend.nodes[label]
+		_, err := readNeo4jCSV(f.path, func(record []string) error {
+			if len(record) != len(f.keys)+2 {
+				return fmt.Errorf("Expected %d columns, got %d", len(f.keys)+2, len(record))
+			}
+			backend.countID(record[0])
+			if !kept[label] {
+				return nil
+			}
+
+			v, err := neo4jVertex(label, f.keys, record)
+			if err != nil {
+				return err
+			}
+			vertices[Neo4jID(record[0])] = v
+			if pkg, ok := v.(*schema.Package); ok {
+				backend.packages[coordination.PackageTuple{Name: pkg.SourceURL, Version: pkg.Version}] = pkg
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, label := range []string{"Functions", "Types", "Fields", "Constants"} {
+		_, err := readNeo4jCSV(backend.rels[label].path, func(record []string) error {
+			src, dst := vertices[Neo4jID(record[0])], vertices[Neo4jID(record[1])]
+			if src == nil || dst == nil {
+				return nil
+			}
+			if f, ok := dst.(*schema.Function); ok {
+				f.Package = src.(*schema.Package)
+			}
+			backend.track(schema.Edge{Source: src, Label: label, Target: dst})
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// neo4jVertex parses a record from the node file of label, whose columns are the id followed by keys
+func neo4jVertex(label string, keys []string, record []string) (schema.Vertex, error) {
+	v, err := schema.NewVertex(label)
+	if err != nil {
+		return nil, err
+	}
+	zeroProps := v.Properties()
+
+	props := map[string]interface{}{}
+	for i, k := range keys {
+		s := record[i+1]
+		switch zeroProps[k].(type) {
+		case int, int64:
+			props[k], err = strconv.ParseInt(s, 10, 64)
+		case float64:
+			props[k], err = strconv.ParseFloat(s, 64)
+		case bool:
+			props[k], err = strconv.ParseBool(s)
+		default:
+			props[k] = s
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Bad %s %s %q: %v", label, k, s, err)
+		}
+	}
+
+	buf, err := json.Marshal(props)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(buf, v); err != nil {
+		return nil, err
+	}
+	v.SetBackendMeta(Neo4jID(record[0]))
+	return v, nil
+}
+
+// vertexID derives v's id from its label and properties, e.g. "package-0123456789abcdef".
+// The n-th vertex with the same content as an earlier one gets "-n" appended.
+// must be called with mtx held
+func (backend *Neo4jCSVBackend) vertexID(v schema.Vertex) string {
+	props := v.Properties()
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00", v.Label())
+	for _, k := range sortedKeys(props) {
+		fmt.Fprintf(h, "%s=%v\x00", k, props[k])
+	}
+
+	id := v.Label() + "-" + hex.EncodeToString(h.Sum(nil))[:16]
+	n := backend.written[id]
+	backend.written[id] = n + 1
+	if n > 0 {
+		id += "-" + strconv.Itoa(n)
+	}
+	return id
+}
+
+// countID records that id was already written, so vertexID doesn't hand it out again
+// must be called with mtx held
+func (backend *Neo4jCSVBackend) countID(id string) {
+	parts := strings.SplitN(id, "-", 3)
+	if len(parts) < 2 {
+		return
+	}
+	base := parts[0] + "-" + parts[1]
+	n := 0
+	if len(parts) == 3 {
+		var err error
+		if n, err = strconv.Atoi(parts[2]); err != nil {
+			return
+		}
+	}
+	if backend.written[base] <= n {
+		backend.written[base] = n + 1
+	}
+}
+
+func (backend *Neo4jCSVBackend) GetPackages() ([]*schema.Package, error) {
+	backend.mtx.Lock()
+	defer backend.mtx.Unlock()
+
+	pkgs := []*schema.Package{}
+	for _, pkg := range backend.packages {
+		pkgs = append(pkgs, pkg)
+	}
+	return pkgs, nil
+}
+
+func (backend *Neo4jCSVBackend) GetPackage(tup coordination.PackageTuple) (*schema.Package, bool, error) {
+	backend.mtx.Lock()
+	defer backend.mtx.Unlock()
+
+	pkg, found := backend.packages[tup]
+	return pkg, found, nil
+}
+
+func (backend *Neo4jCSVBackend) CreatePackage(tup coordination.PackageTuple) (*schema.Package, error) {
+	backend.mtx.Lock()
+	defer backend.mtx.Unlock()
+
+	if _, found := backend.packages[tup]; found {
+		return nil, fmt.Errorf("Package %v already exists", tup)
+	}
+
+	pkg := &schema.Package{
+		SourceURL: tup.Name,
+		Version:   tup.Version,
+	}
+	if err := backend.writeVertex(pkg); err != nil {
+		return nil, err
+	}
+	backend.nodes[pkg.Label()].w.Flush()
+	backend.packages[tup] = pkg
+
+	return pkg, nil
+}
+
+func (backend *Neo4jCSVBackend) PackageFunctions(pkg *schema.Package) (map[string]*schema.Function, error) {
+	backend.mtx.Lock()
+	defer backend.mtx.Unlock()
+
+	functions := map[string]*schema.Function{}
+	for _, f := range backend.functions[pkg] {
+		if err := addFunction(functions, f); err != nil {
+			return nil, err
+		}
+	}
+	return functions, nil
+}
+
+func (backend *Neo4jCSVBackend) PackageTypes(pkg *schema.Package) (map[string]*schema.Type, error) {
+	backend.mtx.Lock()
+	defer backend.mtx.Unlock()
+
+	types := map[string]*schema.Type{}
+	for symbol, t := range backend.types[pkg] {
+		types[symbol] = t
+	}
+	return types, nil
+}
+
+func (backend *Neo4jCSVBackend) PackageFields(pkg *schema.Package) (map[string]*schema.Field, error) {
+	backend.mtx.Lock()
+	defer backend.mtx.Unlock()
+
+	fields := map[string]*schema.Field{}
+	for symbol, t := range backend.types[pkg] {
+		for _, f := range backend.fields[t] {
+			fields[symbol+"."+f.Name] = f
+		}
+	}
+	return fields, nil
+}
+
+func (backend *Neo4jCSVBackend) PackageConstants(pkg *schema.Package) (map[string]*schema.Constant, error) {
+	backend.mtx.Lock()
+	defer backend.mtx.Unlock()
+
+	constants := map[string]*schema.Constant{}
+	for symbol, c := range backend.constants[pkg] {
+		constants[symbol] = c
+	}
+	return constants, nil
+}
+
+// must be called with mtx held
+func (backend *Neo4jCSVBackend) writeVertex(v schema.Vertex) error {
+	label := v.Label()
+	f, ok := backend.nodes[label]
+	if !ok {
+		return fmt.Errorf("No node file for label %q", label)
+	}
+
+	id := backend.vertexID(v)
+	props := v.Properties()
+
+	record := []string{id}
+	for _, k := range f.keys {
+		record = append(record, fmt.Sprint(props[k]))
+	}
+	record = append(record, label)
+
+	if err := f.w.Write(record); err != nil {
+		return err
+	}
+	v.SetBackendMeta(Neo4jID(id))
+	return nil
+}
+
+// must be called with mtx held
+func (backend *Neo4jCSVBackend) writeEdge(edge schema.Edge) error {
+	f, ok := backend.rels[edge.Label]
+	if !ok {
+		return fmt.Errorf("No relationship file for label %q", edge.Label)
+	}
+
+	src, ok := edge.Source.GetBackendMeta().(Neo4jID)
+	if !ok {
+		return fmt.Errorf("Edge %q source %v was never written", edge.Label, edge.Source)
+	}
+	dst, ok := edge.Target.GetBackendMeta().(Neo4jID)
+	if !ok {
+		return fmt.Errorf("Edge %q target %v was never written", edge.Label, edge.Target)
+	}
+
+	// the columns are fixed by schema.EdgeDefinitions, so anything else would be silently lost
+	for k := range edge.Properties {
+		if !slices.Contains(f.keys, k) {
+			return fmt.Errorf("Edge %q property %q isn't declared in schema.EdgeDefinitions", edge.Label, k)
+		}
+	}
+
+	record := []string{string(src), string(dst)}
+	for _, k := range f.keys {
+		if v, ok := edge.Properties[k]; ok {
+			record = append(record, fmt.Sprint(v))
+		} else {
+			record = append(record, "")
+		}
+	}
+	record = append(record, edge.Label)
+
+	if err := f.w.Write(record); err != nil {
+		return err
+	}
+
+	backend.track(edge)
+	return nil
+}
+
+// track keeps track of functions, types, fields and constants so PackageFunctions, PackageTypes, PackageFields and
+// PackageConstants work for packages which have already been written
+// must be called with mtx held
+func (backend *Neo4jCSVBackend) track(edge schema.Edge) {
+	if t, ok := edge.Source.(*schema.Type); ok && edge.Label == "Fields" {
+		if f, ok := edge.Target.(*schema.Field); ok {
+			backend.fields[t] = append(backend.fields[t], f)
+		}
+	}
+	if pkg, ok := edge.Source.(*schema.Package); ok {
+		switch target := edge.Target.(type) {
+		case *schema.Function:
+			backend.functions[pkg] = append(backend.functions[pkg], target)
+		case *schema.Type:
+			if backend.types[pkg] == nil {
+				backend.types[pkg] = map[string]*schema.Type{}
+			}
+			backend.types[pkg][target.Symbol] = target
+		case *schema.Constant:
+			if backend.constants[pkg] == nil {
+				backend.constants[pkg] = map[string]*schema.Constant{}
+			}
+			backend.constants[pkg][target.Symbol] = target
+		}
+	}
+}
+
+func (backend *Neo4jCSVBackend) AddVStream(vertices chan schema.Vertex, progressCb func([]schema.Vertex)) *VertexStream {
+	stream := &VertexStream{}
+	stream.Go(func() error {
+		flush := func(batch []schema.Vertex) error {
+			backend.mtx.Lock()
+			defer backend.mtx.Unlock()
+
+			for _, v := range batch {
+				if err := backend.writeVertex(v); err != nil {
+					return fmt.Errorf("Error writing vertex: %v", err)
+				}
+			}
+			for _, f := range backend.nodes {
+				f.w.Flush()
+				if err := f.w.Error(); err != nil {
+					return fmt.Errorf("Error writing vertices: %v", err)
+				}
+			}
+
+			progressCb(batch)
+			return nil
+		}
+
+		batch := []schema.Vertex{}
+		for v := range vertices {
+			batch = append(batch, v)
+			if len(batch) >= VERTEX_BATCH_SIZE {
+				if err := flush(batch); err != nil {
+					Drain(vertices)
+					return err
+				}
+				batch = []schema.Vertex{}
+			}
+		}
+
+		if len(batch) > 0 {
+			return flush(batch)
+		}
+		return nil
+	})
+
+	return stream
+}
+
+func (backend *Neo4jCSVBackend) AddEBulk(edges []schema.Edge, progressCb func([]schema.Edge)) error {
+	logrus.Infof("Adding %d edges", len(edges))
+
+	for start := 0; start < len(edges); start += EDGE_BATCH_SIZE {
+		end := start + EDGE_BATCH_SIZE
+		if end > len(edges) {
+			end = len(edges)
+		}
+		batch := edges[start:end]
+
+		backend.mtx.Lock()
+		for _, edge := range batch {
+			if err := backend.writeEdge(edge); err != nil {
+				backend.mtx.Unlock()
+				return fmt.Errorf("Error writing edge: %v", err)
+			}
+		}
+		for _, f := range backend.rels {
+			f.w.Flush()
+			if err := f.w.Error(); err != nil {
+				backend.mtx.Unlock()
+				return fmt.Errorf("Error writing edges: %v", err)
+			}
+		}
+		backend.mtx.Unlock()
+
+		progressCb(batch)
+	}
+	return nil
+}
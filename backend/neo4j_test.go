@@ -0,0 +1,68 @@
+package backend_test
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+
+	gbackend "github.com/kallsyms/go-graph/backend"
+	"github.com/kallsyms/go-graph/backend/backendtest"
+	"github.com/kallsyms/go-graph/schema"
+)
+
+func TestNeo4jCSVConformance(t *testing.T) {
+	backendtest.Run(t, func(t *testing.T) gbackend.Backend {
+		b, err := gbackend.NewNeo4jCSVBackend(t.TempDir())
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	})
+}
+
+func TestNeo4jCSVEdgeProperties(t *testing.T) {
+	dir := t.TempDir()
+	b, err := gbackend.NewNeo4jCSVBackend(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	call := &schema.FunctionCall{Kind: "call"}
+	x := &schema.Variable{Name: "x", Type: "int"}
+	vertices := make(chan schema.Vertex)
+	stream := b.AddVStream(vertices, func([]schema.Vertex) {})
+	vertices <- call
+	vertices <- x
+	close(vertices)
+	if err := stream.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	arg := schema.Edge{Source: call, Label: "Arg", Target: x, Properties: map[string]interface{}{"index": 3}}
+	if err := b.AddEBulk([]schema.Edge{arg}, func([]schema.Edge) {}); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(mustOpen(t, filepath.Join(dir, "relationships", "Arg.csv"))).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0][2] != "index:long" || records[1][2] != "3" {
+		t.Errorf("Arg.csv = %q, want an index column holding 3", records)
+	}
+
+	// there's no column for undeclared properties, so they have to be refused rather than dropped
+	arg.Properties["bogus"] = true
+	if err := b.AddEBulk([]schema.Edge{arg}, func([]schema.Edge) {}); err == nil {
+		t.Errorf("AddEBulk wrote an edge with an undeclared property")
+	}
+}
+
+func mustOpen(t *testing.T, path string) *os.File {
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { f.Close() })
+	return f
+}
@@ -0,0 +1,73 @@
+package backend
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Factory connects to (or opens) the backend described by u.
+type Factory func(u *url.URL) (Backend, error)
+
+var (
+	factoriesMtx sync.RWMutex
+	factories    = map[string]Factory{}
+)
+
+// Register makes a backend available to NewBackend for -db URLs with the given scheme.
+// Like database/sql drivers, backends outside this package should call it from an init function, and be imported for
+// side effects by the command that uses them.
+// Register panics if the scheme is already taken.
+func Register(scheme string, factory Factory) {
+	factoriesMtx.Lock()
+	defer factoriesMtx.Unlock()
+
+	scheme = strings.ToLower(scheme)
+	if factory == nil {
+		panic("backend: Register factory is nil for " + scheme)
+	}
+	if _, dup := factories[scheme]; dup {
+		panic("backend: Register called twice for " + scheme)
+	}
+	factories[scheme] = factory
+}
+
+// Schemes returns the sorted list of registered URL schemes.
+func Schemes() []string {
+	factoriesMtx.RLock()
+	defer factoriesMtx.RUnlock()
+
+	schemes := make([]string, 0, len(factories))
+	for scheme := range factories {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// NewBackend picks and connects to a backend based on the scheme of connStr (see the README)
+func NewBackend(connStr string) (Backend, error) {
+	u, err := url.Parse(connStr)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid backend URL %q: %v", connStr, err)
+	}
+
+	factoriesMtx.RLock()
+	factory, ok := factories[u.Scheme]
+	factoriesMtx.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("Unknown backend %q in %q (known: %s)", u.Scheme, connStr, strings.Join(Schemes(), ", "))
+	}
+
+	return factory(u)
+}
+
+// urlPath is the filesystem path in u, which may be relative (sqlite://graph.db) or absolute (sqlite:///tmp/graph.db)
+func urlPath(u *url.URL) string {
+	if u.Opaque != "" {
+		return u.Opaque
+	}
+	return u.Host + u.Path
+}
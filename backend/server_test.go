@@ -0,0 +1,40 @@
+package backend_test
+
+import (
+	"os"
+	"testing"
+
+	gbackend "github.com/kallsyms/go-graph/backend"
+	"github.com/kallsyms/go-graph/backend/backendtest"
+)
+
+// serverConformance runs the conformance suite against the server whose -db URL is in the environment variable env,
+// skipping it if that isn't set. Everything on the server is dropped before each test.
+func serverConformance(t *testing.T, env string) {
+	conn := os.Getenv(env)
+	if conn == "" {
+		t.Skipf("%s isn't set", env)
+	}
+
+	backendtest.Run(t, func(t *testing.T) gbackend.Backend {
+		b, err := gbackend.NewBackend(conn)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := b.DropAll(); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.CreateSchema(); err != nil {
+			t.Fatal(err)
+		}
+		return b
+	})
+}
+
+func TestArangoConformance(t *testing.T) {
+	serverConformance(t, "GO_GRAPH_ARANGO_URL")
+}
+
+func TestGremlinConformance(t *testing.T) {
+	serverConformance(t, "GO_GRAPH_GREMLIN_URL")
+}
@@ -0,0 +1,918 @@
+package backend
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync/atomic"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sirupsen/logrus"
+
+	"github.com/kallsyms/go-graph/coordination"
+	"github.com/kallsyms/go-graph/schema"
+)
+
+// SQLiteID is the backend meta attached to vertices stored in a SQLiteBackend.
+// IDs are unique across all vertex tables.
+type SQLiteID int64
+
+// SQLiteBackend stores the graph in a single SQLite file.
+// Every vertex label gets its own table with one column per property, and every edge label gets an edge_<label>
+// (src, dst) table.
+// The `vertex` table maps every id to its label, so edges can be followed without knowing what they point to.
+type SQLiteBackend struct {
+	db     *sql.DB
+	lastID int64
+}
+
+func init() {
+	Register("sqlite", func(u *url.URL) (Backend, error) {
+		return NewSQLiteBackend(urlPath(u))
+	})
+}
+
+func NewSQLiteBackend(path string) (*SQLiteBackend, error) {
+	db, err := sql.Open("sqlite3", fmt.Sprintf("file:%s?_journal_mode=WAL&_busy_timeout=5000", path))
+	if err != nil {
+		return nil, err
+	}
+	// sqlite only allows a single writer anyways
+	db.SetMaxOpenConns(1)
+
+	backend := &SQLiteBackend{db: db}
+
+	// a fresh file should just work, so always make sure tables exist
+	if err := backend.CreateSchema(); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return backend, nil
+}
+
+func sqliteColumnType(v interface{}) string {
+	switch v.(type) {
+	case int, int64, bool:
+		return "INTEGER"
+	case float64:
+		return "REAL"
+	default:
+		return "TEXT"
+	}
+}
+
+// edge tables are prefixed since sqlite table names are case insensitive (and e.g. "Statement" would clash with "statement")
+func edgeTable(label string) string {
+	return quoteIdent("edge_" + label)
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+func (backend *SQLiteBackend) DropAll() error {
+	// every table, including those of labels older schema versions had
+	rows, err := backend.db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%'")
+	if err != nil {
+		return err
+	}
+	tables := []string{}
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			rows.Close()
+			return err
+		}
+		tables = append(tables, table)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, table := range tables {
+		if _, err := backend.db.Exec("DROP TABLE IF EXISTS " + quoteIdent(table)); err != nil {
+			return err
+		}
+	}
+
+	if _, err := backend.db.Exec("PRAGMA user_version = 0"); err != nil {
+		return err
+	}
+
+	atomic.StoreInt64(&backend.lastID, 0)
+	return nil
+}
+
+// checkSchemaVersion makes sure the db is either empty, or was created with this schema version.
+// CREATE TABLE IF NOT EXISTS won't add any new columns to existing tables, so older dbs can't just be written to.
+func (backend *SQLiteBackend) checkSchemaVersion() error {
+	var version int
+	if err := backend.db.QueryRow("PRAGMA user_version").Scan(&version); err != nil {
+		return err
+	}
+	if version == schema.SchemaVersion {
+		return nil
+	}
+
+	var tables int
+	if err := backend.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table'").Scan(&tables); err != nil {
+		return err
+	}
+	if tables == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("Database has schema version %d, but this go-graph uses schema version %d (delete it to start over)", version, schema.SchemaVersion)
+}
+
+func (backend *SQLiteBackend) CreateSchema() error {
+	if err := backend.checkSchemaVersion(); err != nil {
+		return err
+	}
+
+	stmts := []string{
+		"CREATE TABLE IF NOT EXISTS vertex (id INTEGER PRIMARY KEY, label TEXT NOT NULL)",
+	}
+
+	for _, label := range schema.VertexLabels {
+		v, err := schema.NewVertex(label)
+		if err != nil {
+			// programming error
+			panic(err)
+		}
+
+		props := v.Properties()
+		cols := []string{"id INTEGER PRIMARY KEY"}
+		for _, k := range sortedKeys(props) {
+			cols = append(cols, quoteIdent(k)+" "+sqliteColumnType(props[k]))
+		}
+		stmts = append(stmts, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s)", quoteIdent(label), strings.Join(cols, ", ")))
+	}
+
+	for _, def := range schema.EdgeDefinitions {
+		table := edgeTable(def.Label)
+		stmts = append(stmts,
+			fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (src INTEGER NOT NULL, dst INTEGER NOT NULL, properties TEXT)", table),
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (src)", quoteIdent("edge_"+def.Label+"_src"), table),
+			fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s (dst)", quoteIdent("edge_"+def.Label+"_dst"), table),
+		)
+	}
+
+	// same indexes as the arango backend (so Symbol isn't unique either, see there)
+	stmts = append(stmts,
+		"CREATE UNIQUE INDEX IF NOT EXISTS package_source_version ON package (SourceURL, Version)",
+		"CREATE INDEX IF NOT EXISTS function_name ON function (Name)",
+		"CREATE INDEX IF NOT EXISTS function_symbol ON function (Symbol)",
+		"CREATE INDEX IF NOT EXISTS type_symbol ON type (Symbol)",
+		"CREATE INDEX IF NOT EXISTS constant_symbol ON constant (Symbol)",
+		fmt.Sprintf("PRAGMA user_version = %d", schema.SchemaVersion),
+	)
+
+	for _, stmt := range stmts {
+		if _, err := backend.db.Exec(stmt); err != nil {
+			return fmt.Errorf("Error creating schema (%q): %v", stmt, err)
+		}
+	}
+
+	var lastID int64
+	if err := backend.db.QueryRow("SELECT COALESCE(MAX(id), 0) FROM vertex").Scan(&lastID); err != nil {
+		return err
+	}
+	atomic.StoreInt64(&backend.lastID, lastID)
+
+	return nil
+}
+
+// vertexColumns returns the (aliased) columns to select to read back a vertex with scanVertices
+func vertexColumns(label, alias string) string {
+	v, err := schema.NewVertex(label)
+	if err != nil {
+		panic(err)
+	}
+
+	cols := []string{alias + ".id"}
+	for _, k := range sortedKeys(v.Properties()) {
+		cols = append(cols, alias+"."+quoteIdent(k))
+	}
+	return strings.Join(cols, ", ")
+}
+
+// scanVertices reads vertices out of rows which were selected with vertexColumns
+func scanVertices(label string, rows *sql.Rows) ([]schema.Vertex, error) {
+	defer rows.Close()
+
+	zero, err := schema.NewVertex(label)
+	if err != nil {
+		return nil, err
+	}
+	zeroProps := zero.Properties()
+	keys := sortedKeys(zeroProps)
+
+	vertices := []schema.Vertex{}
+	for rows.Next() {
+		var id int64
+		values := make([]interface{}, len(keys))
+		dest := []interface{}{&id}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		props := map[string]interface{}{}
+		for i, k := range keys {
+			switch val := values[i].(type) {
+			case []byte:
+				props[k] = string(val)
+			case int64:
+				if _, isBool := zeroProps[k].(bool); isBool {
+					props[k] = val != 0
+				} else {
+					props[k] = val
+				}
+			default:
+				props[k] = val
+			}
+		}
+
+		v, _ := schema.NewVertex(label)
+		buf, err := json.Marshal(props)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(buf, v); err != nil {
+			return nil, err
+		}
+		v.SetBackendMeta(SQLiteID(id))
+		vertices = append(vertices, v)
+	}
+
+	return vertices, rows.Err()
+}
+
+func (backend *SQLiteBackend) GetPackages() ([]*schema.Package, error) {
+	rows, err := backend.db.Query(fmt.Sprintf("SELECT %s FROM package p", vertexColumns("package", "p")))
+	if err != nil {
+		return nil, err
+	}
+
+	vertices, err := scanVertices("package", rows)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs := make([]*schema.Package, len(vertices))
+	for i, v := range vertices {
+		pkgs[i] = v.(*schema.Package)
+	}
+	return pkgs, nil
+}
+
+func (backend *SQLiteBackend) GetPackage(tup coordination.PackageTuple) (*schema.Package, bool, error) {
+	var id int64
+	err := backend.db.QueryRow("SELECT id FROM package WHERE SourceURL = ? AND Version = ?", tup.Name, tup.Version).Scan(&id)
+	if err == sql.ErrNoRows {
+		return nil, false, nil
+	} else if err != nil {
+		return nil, false, err
+	}
+
+	pkg := &schema.Package{
+		SourceURL: tup.Name,
+		Version:   tup.Version,
+	}
+	pkg.SetBackendMeta(SQLiteID(id))
+	return pkg, true, nil
+}
+
+func (backend *SQLiteBackend) CreatePackage(tup coordination.PackageTuple) (*schema.Package, error) {
+	pkg := &schema.Package{
+		SourceURL: tup.Name,
+		Version:   tup.Version,
+	}
+
+	if err := backend.insertVertices([]schema.Vertex{pkg}); err != nil {
+		return nil, err
+	}
+
+	return pkg, nil
+}
+
+func (backend *SQLiteBackend) PackageFunctions(pkg *schema.Package) (map[string]*schema.Function, error) {
+	rows, err := backend.db.Query(
+		fmt.Sprintf(`SELECT %s FROM edge_Functions e JOIN function f ON f.id = e.dst WHERE e.src = ?`, vertexColumns("function", "f")),
+		int64(pkg.GetBackendMeta().(SQLiteID)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	vertices, err := scanVertices("function", rows)
+	if err != nil {
+		return nil, err
+	}
+
+	functions := map[string]*schema.Function{}
+	for _, v := range vertices {
+		f := v.(*schema.Function)
+		f.Package = pkg
+		if err := addFunction(functions, f); err != nil {
+			return nil, err
+		}
+	}
+
+	return functions, nil
+}
+
+func (backend *SQLiteBackend) PackageTypes(pkg *schema.Package) (map[string]*schema.Type, error) {
+	rows, err := backend.db.Query(
+		fmt.Sprintf(`SELECT %s FROM edge_Types e JOIN type t ON t.id = e.dst WHERE e.src = ?`, vertexColumns("type", "t")),
+		int64(pkg.GetBackendMeta().(SQLiteID)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	vertices, err := scanVertices("type", rows)
+	if err != nil {
+		return nil, err
+	}
+
+	types := map[string]*schema.Type{}
+	for _, v := range vertices {
+		t := v.(*schema.Type)
+		types[t.Symbol] = t
+	}
+
+	return types, nil
+}
+
+func (backend *SQLiteBackend) PackageFields(pkg *schema.Package) (map[string]*schema.Field, error) {
+	const fromPkgFields = `FROM edge_Types et JOIN type t ON t.id = et.dst JOIN edge_Fields ef ON ef.src = t.id JOIN field f ON f.id = ef.dst WHERE et.src = ?`
+
+	// which types each field belongs to (a type defined as another struct type shares its fields)
+	rows, err := backend.db.Query(`SELECT f.id, t."Symbol" `+fromPkgFields, int64(pkg.GetBackendMeta().(SQLiteID)))
+	if err != nil {
+		return nil, err
+	}
+	owners := map[SQLiteID][]string{}
+	for rows.Next() {
+		var id int64
+		var symbol string
+		if err := rows.Scan(&id, &symbol); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		owners[SQLiteID(id)] = append(owners[SQLiteID(id)], symbol)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = backend.db.Query(
+		fmt.Sprintf(`SELECT DISTINCT %s `+fromPkgFields, vertexColumns("field", "f")),
+		int64(pkg.GetBackendMeta().(SQLiteID)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	vertices, err := scanVertices("field", rows)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := map[string]*schema.Field{}
+	for _, v := range vertices {
+		f := v.(*schema.Field)
+		for _, symbol := range owners[f.GetBackendMeta().(SQLiteID)] {
+			fields[symbol+"."+f.Name] = f
+		}
+	}
+
+	return fields, nil
+}
+
+func (backend *SQLiteBackend) PackageConstants(pkg *schema.Package) (map[string]*schema.Constant, error) {
+	rows, err := backend.db.Query(
+		fmt.Sprintf(`SELECT %s FROM edge_Constants e JOIN constant c ON c.id = e.dst WHERE e.src = ?`, vertexColumns("constant", "c")),
+		int64(pkg.GetBackendMeta().(SQLiteID)),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	vertices, err := scanVertices("constant", rows)
+	if err != nil {
+		return nil, err
+	}
+
+	constants := map[string]*schema.Constant{}
+	for _, v := range vertices {
+		c := v.(*schema.Constant)
+		constants[c.Symbol] = c
+	}
+
+	return constants, nil
+}
+
+// insertVertices inserts all vertices in a single transaction, setting their backend meta on success
+func (backend *SQLiteBackend) insertVertices(vertices []schema.Vertex) error {
+	tx, err := backend.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	ids := make([]int64, len(vertices))
+	for i, v := range vertices {
+		ids[i] = atomic.AddInt64(&backend.lastID, 1)
+
+		if _, err := tx.Exec("INSERT INTO vertex (id, label) VALUES (?, ?)", ids[i], v.Label()); err != nil {
+			return err
+		}
+
+		props := v.Properties()
+		keys := sortedKeys(props)
+		cols := []string{"id"}
+		placeholders := []string{"?"}
+		args := []interface{}{ids[i]}
+		for _, k := range keys {
+			cols = append(cols, quoteIdent(k))
+			placeholders = append(placeholders, "?")
+			args = append(args, props[k])
+		}
+
+		query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)", quoteIdent(v.Label()), strings.Join(cols, ", "), strings.Join(placeholders, ", "))
+		if _, err := tx.Exec(query, args...); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return err
+	}
+
+	for i, v := range vertices {
+		v.SetBackendMeta(SQLiteID(ids[i]))
+	}
+	return nil
+}
+
+func (backend *SQLiteBackend) AddVStream(vertices chan schema.Vertex, progressCb func([]schema.Vertex)) *VertexStream {
+	stream := &VertexStream{}
+	stream.Go(func() error {
+		flush := func(batch []schema.Vertex) error {
+			if err := backend.insertVertices(batch); err != nil {
+				return fmt.Errorf("Error inserting vertices: %v", err)
+			}
+			progressCb(batch)
+			return nil
+		}
+
+		batch := []schema.Vertex{}
+		for v := range vertices {
+			batch = append(batch, v)
+			if len(batch) >= VERTEX_BATCH_SIZE {
+				if err := flush(batch); err != nil {
+					Drain(vertices)
+					return err
+				}
+				batch = []schema.Vertex{}
+			}
+		}
+
+		if len(batch) > 0 {
+			return flush(batch)
+		}
+		return nil
+	})
+
+	return stream
+}
+
+func (backend *SQLiteBackend) insertEdges(edges []schema.Edge) error {
+	tx, err := backend.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, edge := range edges {
+		src, ok := edge.Source.GetBackendMeta().(SQLiteID)
+		if !ok {
+			return fmt.Errorf("Edge %q source %v has no id", edge.Label, edge.Source)
+		}
+		dst, ok := edge.Target.GetBackendMeta().(SQLiteID)
+		if !ok {
+			return fmt.Errorf("Edge %q target %v has no id", edge.Label, edge.Target)
+		}
+
+		var props interface{}
+		if len(edge.Properties) > 0 {
+			buf, err := json.Marshal(edge.Properties)
+			if err != nil {
+				return err
+			}
+			props = string(buf)
+		}
+
+		query := fmt.Sprintf("INSERT INTO %s (src, dst, properties) VALUES (?, ?, ?)", edgeTable(edge.Label))
+		if _, err := tx.Exec(query, int64(src), int64(dst), props); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (backend *SQLiteBackend) AddEBulk(edges []schema.Edge, progressCb func([]schema.Edge)) error {
+	logrus.Infof("Adding %d edges", len(edges))
+
+	for start := 0; start < len(edges); start += EDGE_BATCH_SIZE {
+		end := start + EDGE_BATCH_SIZE
+		if end > len(edges) {
+			end = len(edges)
+		}
+		batch := edges[start:end]
+
+		if err := backend.insertEdges(batch); err != nil {
+			return fmt.Errorf("Error inserting edges: %v", err)
+		}
+		progressCb(batch)
+	}
+	return nil
+}
+
+func (backend *SQLiteBackend) vertexByID(label string, id int64) (schema.Vertex, error) {
+	rows, err := backend.db.Query(fmt.Sprintf("SELECT %s FROM %s t WHERE t.id = ?", vertexColumns(label, "t"), quoteIdent(label)), id)
+	if err != nil {
+		return nil, err
+	}
+
+	vertices, err := scanVertices(label, rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(vertices) == 0 {
+		return nil, fmt.Errorf("No %s with id %d", label, id)
+	}
+	return vertices[0], nil
+}
+
+// traverse follows edges with the given label from v. from and to are the edge table columns to go from and to.
+func (backend *SQLiteBackend) traverse(v schema.Vertex, label string, from string, to string) ([]schema.Edge, error) {
+	if err := checkEdgeLabel(label); err != nil {
+		return nil, err
+	}
+
+	id, ok := v.GetBackendMeta().(SQLiteID)
+	if !ok {
+		return nil, fmt.Errorf("Vertex %v is not in this backend", v)
+	}
+
+	rows, err := backend.db.Query(
+		fmt.Sprintf("SELECT e.%s, v.label, e.properties FROM %s e JOIN vertex v ON v.id = e.%s WHERE e.%s = ?", to, edgeTable(label), to, from),
+		int64(id),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	type found struct {
+		id    int64
+		label string
+		props map[string]interface{}
+	}
+	results := []found{}
+	for rows.Next() {
+		var f found
+		var props sql.NullString
+		if err := rows.Scan(&f.id, &f.label, &props); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if props.Valid {
+			if err := json.Unmarshal([]byte(props.String), &f.props); err != nil {
+				rows.Close()
+				return nil, err
+			}
+		}
+		results = append(results, f)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	edges := []schema.Edge{}
+	for _, f := range results {
+		other, err := backend.vertexByID(f.label, f.id)
+		if err != nil {
+			return nil, err
+		}
+
+		edge := schema.Edge{Source: v, Label: label, Target: other, Properties: f.props}
+		if from == "dst" {
+			edge.Source, edge.Target = other, v
+		}
+		edges = append(edges, edge)
+	}
+
+	return edges, nil
+}
+
+func (backend *SQLiteBackend) OutEdges(v schema.Vertex, label string) ([]schema.Edge, error) {
+	return backend.traverse(v, label, "src", "dst")
+}
+
+func (backend *SQLiteBackend) InEdges(v schema.Vertex, label string) ([]schema.Edge, error) {
+	return backend.traverse(v, label, "dst", "src")
+}
+
+// The helpers below are the README sample queries, expressed in SQL.
+
+// functionsNamed selects the ids of every function named funcName in any version of the package pkgPath.
+// funcName can also be a Symbol, to pick out one method (e.g. "example.com/pkg.(*T).Close") rather than all of them.
+const functionsNamed = `
+SELECT f.id FROM package p
+JOIN edge_Functions fe ON fe.src = p.id
+JOIN function f ON f.id = fe.dst
+WHERE p.SourceURL = ? AND ? IN (f.Name, f.Symbol)`
+
+// CallSites returns every statement which calls funcName in the package pkgPath.
+func (backend *SQLiteBackend) CallSites(pkgPath, funcName string) ([]*schema.Statement, error) {
+	rows, err := backend.db.Query(fmt.Sprintf(`
+SELECT DISTINCT %s FROM edge_Callee c
+JOIN edge_CallSiteStatement cs ON cs.src = c.src
+JOIN statement s ON s.id = cs.dst
+WHERE c.dst IN (%s)`, vertexColumns("statement", "s"), functionsNamed),
+		pkgPath, funcName,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanStatements(rows)
+}
+
+// TransitiveCallees returns every function reachable by up to maxDepth calls from funcName in the package pkgPath.
+func (backend *SQLiteBackend) TransitiveCallees(pkgPath, funcName string, maxDepth int) ([]*schema.Function, error) {
+	rows, err := backend.db.Query(fmt.Sprintf(`
+WITH RECURSIVE reach(id, depth) AS (
+	SELECT id, 0 FROM (%s)
+	UNION
+	SELECT ce.dst, reach.depth + 1 FROM reach
+	JOIN edge_Calls c ON c.src = reach.id
+	JOIN edge_Callee ce ON ce.src = c.dst
+	WHERE reach.depth < ?
+)
+SELECT %s FROM function f WHERE f.id IN (SELECT id FROM reach WHERE depth > 0)`, functionsNamed, vertexColumns("function", "f")),
+		pkgPath, funcName, maxDepth,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	vertices, err := scanVertices("function", rows)
+	if err != nil {
+		return nil, err
+	}
+
+	functions := make([]*schema.Function, len(vertices))
+	for i, v := range vertices {
+		functions[i] = v.(*schema.Function)
+	}
+	return functions, nil
+}
+
+// TransitiveImports returns every package the package pkgPath at version depends on, directly or not.
+func (backend *SQLiteBackend) TransitiveImports(pkgPath, version string) ([]*schema.Package, error) {
+	rows, err := backend.db.Query(fmt.Sprintf(`
+WITH RECURSIVE reach(id) AS (
+	SELECT i.dst FROM package p
+	JOIN edge_Imports i ON i.src = p.id
+	WHERE p.SourceURL = ? AND p.Version = ?
+	UNION
+	SELECT i.dst FROM reach
+	JOIN edge_Imports i ON i.src = reach.id
+)
+SELECT %s FROM package p WHERE p.id IN (SELECT id FROM reach)`, vertexColumns("package", "p")),
+		pkgPath, version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanPackages(rows)
+}
+
+// Importers returns every package which directly imports the package pkgPath at version.
+func (backend *SQLiteBackend) Importers(pkgPath, version string) ([]*schema.Package, error) {
+	rows, err := backend.db.Query(fmt.Sprintf(`
+SELECT DISTINCT %s FROM package dep
+JOIN edge_Imports i ON i.dst = dep.id
+JOIN package p ON p.id = i.src
+WHERE dep.SourceURL = ? AND dep.Version = ?`, vertexColumns("package", "p")),
+		pkgPath, version,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanPackages(rows)
+}
+
+// StatementPaths returns every path of statements through funcName in the package pkgPath, starting at its first
+// statement and following Next edges (but not back edges) for up to maxDepth steps.
+func (backend *SQLiteBackend) StatementPaths(pkgPath, funcName string, maxDepth int) ([][]*schema.Statement, error) {
+	// paths are tracked as ",1,2,3," so that revisits can be found with instr
+	rows, err := backend.db.Query(fmt.Sprintf(`
+WITH RECURSIVE fwd(src, dst) AS (
+	SELECT src, dst FROM edge_Next WHERE NOT COALESCE(json_extract(properties, '$.isBackEdge'), 0)
+),
+paths(id, path, depth) AS (
+	SELECT fs.dst, ',' || fs.dst || ',', 0 FROM edge_FirstStatement fs WHERE fs.src IN (%s)
+	UNION ALL
+	SELECT fwd.dst, paths.path || fwd.dst || ',', paths.depth + 1 FROM paths
+	JOIN fwd ON fwd.src = paths.id
+	WHERE paths.depth < ? AND instr(paths.path, ',' || fwd.dst || ',') = 0
+)
+SELECT path FROM paths
+WHERE depth = ? OR NOT EXISTS (SELECT 1 FROM fwd WHERE fwd.src = paths.id)`, functionsNamed),
+		pkgPath, funcName, maxDepth, maxDepth,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	idPaths := [][]int64{}
+	allIDs := map[int64]bool{}
+	for rows.Next() {
+		var path string
+		if err := rows.Scan(&path); err != nil {
+			return nil, err
+		}
+
+		ids := []int64{}
+		for _, s := range strings.Split(strings.Trim(path, ","), ",") {
+			id, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+			allIDs[id] = true
+		}
+		idPaths = append(idPaths, ids)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
+
+	stmts, err := backend.statementsByID(allIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := make([][]*schema.Statement, len(idPaths))
+	for i, ids := range idPaths {
+		for _, id := range ids {
+			paths[i] = append(paths[i], stmts[id])
+		}
+	}
+	return paths, nil
+}
+
+func (backend *SQLiteBackend) statementsByID(ids map[int64]bool) (map[int64]*schema.Statement, error) {
+	stmts := map[int64]*schema.Statement{}
+	if len(ids) == 0 {
+		return stmts, nil
+	}
+
+	placeholders := []string{}
+	args := []interface{}{}
+	for id := range ids {
+		placeholders = append(placeholders, "?")
+		args = append(args, id)
+	}
+
+	rows, err := backend.db.Query(
+		fmt.Sprintf("SELECT %s FROM statement s WHERE s.id IN (%s)", vertexColumns("statement", "s"), strings.Join(placeholders, ", ")),
+		args...,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	found, err := scanStatements(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range found {
+		stmts[int64(s.GetBackendMeta().(SQLiteID))] = s
+	}
+	return stmts, nil
+}
+
+func scanStatements(rows *sql.Rows) ([]*schema.Statement, error) {
+	vertices, err := scanVertices("statement", rows)
+	if err != nil {
+		return nil, err
+	}
+
+	stmts := make([]*schema.Statement, len(vertices))
+	for i, v := range vertices {
+		stmts[i] = v.(*schema.Statement)
+	}
+	return stmts, nil
+}
+
+func scanPackages(rows *sql.Rows) ([]*schema.Package, error) {
+	vertices, err := scanVertices("package", rows)
+	if err != nil {
+		return nil, err
+	}
+
+	pkgs := make([]*schema.Package, len(vertices))
+	for i, v := range vertices {
+		pkgs[i] = v.(*schema.Package)
+	}
+	return pkgs, nil
+}
+
+func (backend *SQLiteBackend) ScanVertices(cb func(schema.Vertex) error) error {
+	for _, label := range schema.VertexLabels {
+		rows, err := backend.db.Query(fmt.Sprintf("SELECT %s FROM %s t ORDER BY t.id", vertexColumns(label, "t"), quoteIdent(label)))
+		if err != nil {
+			return err
+		}
+
+		// TODO: this reads a whole table at a time
+		vertices, err := scanVertices(label, rows)
+		if err != nil {
+			return err
+		}
+		for _, v := range vertices {
+			if err := cb(v); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (backend *SQLiteBackend) ScanEdges(cb func(schema.Edge) error) error {
+	for _, def := range schema.EdgeDefinitions {
+		rows, err := backend.db.Query(fmt.Sprintf("SELECT e.src, sv.label, e.dst, dv.label, e.properties FROM %s e JOIN vertex sv ON sv.id = e.src JOIN vertex dv ON dv.id = e.dst", edgeTable(def.Label)))
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var src, dst int64
+			var srcLabel, dstLabel string
+			var props sql.NullString
+			if err := rows.Scan(&src, &srcLabel, &dst, &dstLabel, &props); err != nil {
+				rows.Close()
+				return err
+			}
+
+			edge := schema.Edge{Label: def.Label}
+			if edge.Source, err = schema.NewVertex(srcLabel); err != nil {
+				rows.Close()
+				return err
+			}
+			edge.Source.SetBackendMeta(SQLiteID(src))
+			if edge.Target, err = schema.NewVertex(dstLabel); err != nil {
+				rows.Close()
+				return err
+			}
+			edge.Target.SetBackendMeta(SQLiteID(dst))
+			if props.Valid {
+				if err := json.Unmarshal([]byte(props.String), &edge.Properties); err != nil {
+					rows.Close()
+					return err
+				}
+			}
+
+			if err := cb(edge); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
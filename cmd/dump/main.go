@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	gbackend "github.com/kallsyms/go-graph/backend"
+	"github.com/kallsyms/go-graph/snapshot"
+)
+
+// Dump the whole graph to a gzipped JSON Lines file, which can be loaded back with restore.
+func main() {
+	conn := flag.String("db", "ws://localhost:8182", "DB URL, the scheme picks the backend (see README)")
+	output := flag.String("o", "-", "Output file")
+
+	flag.Parse()
+
+	backend, err := gbackend.NewBackend(*conn)
+	if err != nil {
+		logrus.Fatalf("Error creating backend: %v", err)
+	}
+
+	enumerator, ok := backend.(gbackend.Enumerator)
+	if !ok {
+		logrus.Fatalf("Backend %T can't be dumped", backend)
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "-" {
+		fh, err := os.Create(*output)
+		if err != nil {
+			logrus.Fatalf("Error creating %q: %v", *output, err)
+		}
+		defer fh.Close()
+		out = fh
+	}
+
+	if err := snapshot.Dump(out, enumerator); err != nil {
+		logrus.Fatal(err)
+	}
+}
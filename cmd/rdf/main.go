@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	gbackend "github.com/kallsyms/go-graph/backend"
+	"github.com/kallsyms/go-graph/rdf"
+)
+
+// Export the whole graph as RDF for SPARQL triple stores, or print the ontology describing it.
+func main() {
+	conn := flag.String("db", "ws://localhost:8182", "DB URL, the scheme picks the backend (see README)")
+	format := flag.String("format", "nt", "Output format (nt, ttl, or ontology to just print the vocabulary)")
+	base := flag.String("base", rdf.DEFAULT_BASE, "Base IRI for packages, functions, etc.")
+	output := flag.String("o", "-", "Output file")
+
+	flag.Parse()
+
+	var out io.Writer = os.Stdout
+	if *output != "-" {
+		fh, err := os.Create(*output)
+		if err != nil {
+			logrus.Fatalf("Error creating %q: %v", *output, err)
+		}
+		defer fh.Close()
+		out = fh
+	}
+
+	if *format == "ontology" {
+		if err := rdf.WriteOntology(out); err != nil {
+			logrus.Fatal(err)
+		}
+		return
+	}
+
+	backend, err := gbackend.NewBackend(*conn)
+	if err != nil {
+		logrus.Fatalf("Error creating backend: %v", err)
+	}
+
+	enumerator, ok := backend.(gbackend.Enumerator)
+	if !ok {
+		logrus.Fatalf("Backend %T can't be exported (neo4j-csv output has to be imported into Neo4j first)", backend)
+	}
+
+	g, err := rdf.Build(enumerator, *base)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	switch *format {
+	case "nt":
+		err = g.WriteNTriples(out)
+	case "ttl":
+		err = g.WriteTurtle(out)
+	default:
+		logrus.Fatalf("Unknown format %q", *format)
+	}
+	if err != nil {
+		logrus.Fatalf("Error writing RDF: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	gbackend "github.com/kallsyms/go-graph/backend"
+	"github.com/kallsyms/go-graph/snapshot"
+)
+
+// Load a dump made by the dump command into a backend.
+func main() {
+	conn := flag.String("db", "ws://localhost:8182", "DB URL, the scheme picks the backend (see README)")
+	input := flag.String("i", "-", "Dump file")
+	init := flag.Bool("init", false, "Drop everything and create the schema before restoring")
+
+	flag.Parse()
+
+	backend, err := gbackend.NewBackend(*conn)
+	if err != nil {
+		logrus.Fatalf("Error creating backend: %v", err)
+	}
+
+	if *init {
+		if err := backend.DropAll(); err != nil {
+			logrus.Fatalf("Error dropping everything: %v", err)
+		}
+		if err := backend.CreateSchema(); err != nil {
+			logrus.Fatalf("Error creating schema: %v", err)
+		}
+	}
+
+	var in io.Reader = os.Stdin
+	if *input != "-" {
+		fh, err := os.Open(*input)
+		if err != nil {
+			logrus.Fatalf("Error opening %q: %v", *input, err)
+		}
+		defer fh.Close()
+		in = fh
+	}
+
+	if err := snapshot.Restore(in, backend); err != nil {
+		logrus.Fatal(err)
+	}
+}
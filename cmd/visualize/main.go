@@ -0,0 +1,72 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"os"
+
+	"github.com/sirupsen/logrus"
+
+	gbackend "github.com/kallsyms/go-graph/backend"
+	"github.com/kallsyms/go-graph/export"
+)
+
+// Render a function's CFG and call neighbourhood, straight from what's stored in the graph.
+func main() {
+	conn := flag.String("db", "ws://localhost:8182", "DB URL, the scheme picks the backend (see README)")
+	pkgPath := flag.String("pkg", "", "Package path (SourceURL) the function is in")
+	version := flag.String("version", "", "Package version, if more than one is ingested")
+	funcName := flag.String("func", "", "Function name or symbol (e.g. \"(*T).M\")")
+	depth := flag.Int("depth", 1, "How many calls away to include callers and callees")
+	format := flag.String("format", "dot", "Output format (dot or graphml)")
+	output := flag.String("o", "-", "Output file")
+
+	flag.Parse()
+
+	if *pkgPath == "" || *funcName == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	backend, err := gbackend.NewBackend(*conn)
+	if err != nil {
+		logrus.Fatalf("Error creating backend: %v", err)
+	}
+
+	traverser, ok := backend.(gbackend.Traverser)
+	if !ok {
+		logrus.Fatalf("Backend %T can't be traversed", backend)
+	}
+
+	fn, err := export.FindFunction(backend, *pkgPath, *version, *funcName)
+	if err != nil {
+		logrus.Fatal(err)
+	}
+
+	sg, err := export.FunctionNeighbourhood(traverser, fn, *depth)
+	if err != nil {
+		logrus.Fatalf("Error walking graph: %v", err)
+	}
+
+	var out io.Writer = os.Stdout
+	if *output != "-" {
+		fh, err := os.Create(*output)
+		if err != nil {
+			logrus.Fatalf("Error creating %q: %v", *output, err)
+		}
+		defer fh.Close()
+		out = fh
+	}
+
+	switch *format {
+	case "dot":
+		err = export.WriteDOT(out, sg)
+	case "graphml":
+		err = export.WriteGraphML(out, sg)
+	default:
+		logrus.Fatalf("Unknown format %q", *format)
+	}
+	if err != nil {
+		logrus.Fatalf("Error writing %s: %v", *format, err)
+	}
+}
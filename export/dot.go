@@ -0,0 +1,90 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kallsyms/go-graph/schema"
+)
+
+// longer statements are cut off so the rendered graph stays readable
+const DOT_LABEL_LIMIT = 80
+
+func dotEscape(s string) string {
+	// statement text is cut off at a byte limit when it's ingested, which can split a rune
+	s = strings.ToValidUTF8(s, "\uFFFD")
+	if len(s) > DOT_LABEL_LIMIT {
+		// don't split one ourselves either
+		cut := DOT_LABEL_LIMIT
+		for !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		s = s[:cut] + "..."
+	}
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	// \l is a left-justified line break
+	s = strings.ReplaceAll(s, "\n", `\l`)
+	return s
+}
+
+// WriteDOT renders sg as a graphviz digraph.
+// The root function's CFG is drawn as a cluster, back edges are dashed and red, select cases are labelled, and calls are
+// blue.
+func WriteDOT(out io.Writer, sg *Subgraph) error {
+	w := bufio.NewWriter(out)
+
+	fmt.Fprintf(w, "digraph \"%s\" {\n", dotEscape(displayLabel(sg.Root)))
+	fmt.Fprintf(w, "\tnode [fontname=\"monospace\"];\n")
+
+	fmt.Fprintf(w, "\tsubgraph cluster_cfg {\n")
+	fmt.Fprintf(w, "\t\tlabel=\"%s\";\n", dotEscape(displayLabel(sg.Root)))
+	for _, v := range sg.Vertices {
+		if _, ok := v.(*schema.Statement); ok {
+			fmt.Fprintf(w, "\t\t%s [shape=box, label=\"%s\\l\"];\n", sg.ID(v), dotEscape(displayLabel(v)))
+		}
+	}
+	fmt.Fprintf(w, "\t}\n")
+
+	for _, v := range sg.Vertices {
+		switch v.(type) {
+		case *schema.Statement:
+			continue
+		case *schema.Function:
+			style := ""
+			if v == sg.Root {
+				style = ", style=bold"
+			}
+			fmt.Fprintf(w, "\t%s [shape=ellipse%s, label=\"%s\"];\n", sg.ID(v), style, dotEscape(displayLabel(v)))
+		default:
+			fmt.Fprintf(w, "\t%s [label=\"%s\"];\n", sg.ID(v), dotEscape(displayLabel(v)))
+		}
+	}
+
+	for _, e := range sg.Edges {
+		attrs := []string{}
+		switch {
+		case e.Label == "Next" && isBackEdge(e):
+			attrs = append(attrs, "style=dashed", "color=red", "constraint=false")
+		case e.Label == "Calls":
+			attrs = append(attrs, "color=blue")
+		case e.Label != "Next":
+			attrs = append(attrs, fmt.Sprintf("label=\"%s\"", dotEscape(e.Label)))
+		}
+		if selectCase, _ := e.Properties["selectCase"].(string); e.Label == "Next" && selectCase != "" {
+			attrs = append(attrs, fmt.Sprintf("label=\"%s\"", dotEscape(selectCase)))
+		}
+
+		fmt.Fprintf(w, "\t%s -> %s", sg.ID(e.Source), sg.ID(e.Target))
+		if len(attrs) > 0 {
+			fmt.Fprintf(w, " [%s]", strings.Join(attrs, ", "))
+		}
+		fmt.Fprintf(w, ";\n")
+	}
+
+	fmt.Fprintf(w, "}\n")
+	return w.Flush()
+}
@@ -0,0 +1,191 @@
+package export
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	gbackend "github.com/kallsyms/go-graph/backend"
+	"github.com/kallsyms/go-graph/coordination"
+	"github.com/kallsyms/go-graph/schema"
+)
+
+// testGraph stores a call chain top -> mid -> Run -> leaf -> deeper, where Run has a loop (s0 -> s1 -> s2 -> s1, and
+// s1 -> s3) and calls leaf from s2
+func testGraph(t *testing.T) (*gbackend.MemoryBackend, map[string]*schema.Function, []*schema.Statement) {
+	backend := gbackend.NewMemoryBackend()
+	pkg, err := backend.CreatePackage(coordination.PackageTuple{Name: "example.com/p", Version: "v1.0.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vertices := make(chan schema.Vertex)
+	stream := backend.AddVStream(vertices, func([]schema.Vertex) {})
+	edges := []schema.Edge{}
+
+	functions := map[string]*schema.Function{}
+	for _, name := range []string{"top", "mid", "Run", "leaf", "deeper"} {
+		f := &schema.Function{Name: name, Symbol: "example.com/p." + name}
+		functions[name] = f
+		vertices <- f
+		edges = append(edges, schema.Edge{Source: pkg, Label: "Functions", Target: f})
+	}
+
+	stmts := []*schema.Statement{}
+	for i := 0; i < 4; i++ {
+		s := &schema.Statement{Offset: i, Text: fmt.Sprintf("s%d", i)}
+		stmts = append(stmts, s)
+		vertices <- s
+		edges = append(edges, schema.Edge{Source: functions["Run"], Label: "Statement", Target: s})
+	}
+	next := func(from, to int, back bool) schema.Edge {
+		return schema.Edge{Source: stmts[from], Label: "Next", Target: stmts[to], Properties: map[string]interface{}{"isBackEdge": back, "selectCase": ""}}
+	}
+	edges = append(edges,
+		schema.Edge{Source: functions["Run"], Label: "FirstStatement", Target: stmts[0]},
+		next(0, 1, false),
+		next(1, 2, false),
+		next(2, 1, true),
+		next(1, 3, false),
+	)
+
+	call := func(caller, callee string, site *schema.Statement) {
+		fc := &schema.FunctionCall{Kind: "call"}
+		vertices <- fc
+		edges = append(edges,
+			schema.Edge{Source: functions[caller], Label: "Calls", Target: fc},
+			schema.Edge{Source: fc, Label: "Callee", Target: functions[callee]},
+		)
+		if site != nil {
+			edges = append(edges, schema.Edge{Source: fc, Label: "CallSiteStatement", Target: site})
+		}
+	}
+	call("top", "mid", nil)
+	call("mid", "Run", nil)
+	call("Run", "leaf", stmts[2])
+	call("leaf", "deeper", nil)
+
+	close(vertices)
+	if err := stream.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if err := backend.AddEBulk(edges, func([]schema.Edge) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	return backend, functions, stmts
+}
+
+func functionNames(sg *Subgraph) []string {
+	names := []string{}
+	for _, v := range sg.Vertices {
+		if f, ok := v.(*schema.Function); ok {
+			names = append(names, f.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestFunctionNeighbourhoodDepth(t *testing.T) {
+	backend, functions, _ := testGraph(t)
+
+	for depth, want := range []string{
+		"Run leaf",
+		"Run leaf mid",
+		"Run deeper leaf mid top",
+		"Run deeper leaf mid top",
+	} {
+		sg, err := FunctionNeighbourhood(backend, functions["Run"], depth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// leaf is always there, since it's called from Run's CFG
+		if got := strings.Join(functionNames(sg), " "); got != want {
+			t.Errorf("depth %d: functions = %q, want %q", depth, got, want)
+		}
+	}
+}
+
+func TestWriteDOT(t *testing.T) {
+	backend, functions, stmts := testGraph(t)
+	sg, err := FunctionNeighbourhood(backend, functions["Run"], 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDOT(&buf, sg); err != nil {
+		t.Fatal(err)
+	}
+	dot := buf.String()
+
+	for _, want := range []string{
+		fmt.Sprintf("\t%s -> %s [style=dashed, color=red, constraint=false];\n", sg.ID(stmts[2]), sg.ID(stmts[1])),
+		fmt.Sprintf("\t%s -> %s;\n", sg.ID(stmts[0]), sg.ID(stmts[1])),
+		fmt.Sprintf("\t%s -> %s [color=blue];\n", sg.ID(stmts[2]), sg.ID(functions["leaf"])),
+		fmt.Sprintf("\t%s -> %s [color=blue];\n", sg.ID(functions["mid"]), sg.ID(functions["Run"])),
+		fmt.Sprintf("\t%s [shape=ellipse, style=bold, label=\"Run\"];\n", sg.ID(functions["Run"])),
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("DOT output is missing %q:\n%s", want, dot)
+		}
+	}
+	if n := strings.Count(dot, "style=dashed"); n != 1 {
+		t.Errorf("DOT output has %d dashed edges, want only the back edge:\n%s", n, dot)
+	}
+}
+
+func TestWriteGraphML(t *testing.T) {
+	backend, functions, _ := testGraph(t)
+	sg, err := FunctionNeighbourhood(backend, functions["Run"], 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteGraphML(&buf, sg); err != nil {
+		t.Fatal(err)
+	}
+
+	var doc graphMLDoc
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Graph.Nodes) != len(sg.Vertices) || len(doc.Graph.Edges) != len(sg.Edges) {
+		t.Errorf("GraphML has %d nodes and %d edges, want %d and %d", len(doc.Graph.Nodes), len(doc.Graph.Edges), len(sg.Vertices), len(sg.Edges))
+	}
+
+	backEdges := 0
+	for _, e := range doc.Graph.Edges {
+		for _, d := range e.Data {
+			if d.Key == "e_isBackEdge" && d.Value == "true" {
+				backEdges++
+			}
+		}
+	}
+	if backEdges != 1 {
+		t.Errorf("GraphML has %d edges with isBackEdge, want 1", backEdges)
+	}
+}
+
+func TestDotEscapeTruncatesOnRunes(t *testing.T) {
+	// each é is 2 bytes, so the byte limit falls in the middle of one
+	s := "x" + strings.Repeat("é", DOT_LABEL_LIMIT)
+	got := dotEscape(s)
+	if !utf8.ValidString(got) {
+		t.Errorf("dotEscape(%q) = %q, which isn't valid UTF-8", s, got)
+	}
+	if !strings.HasSuffix(got, "é...") {
+		t.Errorf("dotEscape(%q) = %q, want it cut after a whole rune", s, got)
+	}
+
+	// text which was already cut mid-rune when it was ingested
+	if got := dotEscape("\"é"[:2]); got != "\\\"\uFFFD" {
+		t.Errorf("dotEscape of a split rune = %q", got)
+	}
+}
@@ -0,0 +1,130 @@
+package export
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+)
+
+type graphMLKey struct {
+	ID       string `xml:"id,attr"`
+	For      string `xml:"for,attr"`
+	AttrName string `xml:"attr.name,attr"`
+	AttrType string `xml:"attr.type,attr"`
+}
+
+type graphMLData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphMLNode struct {
+	ID   string        `xml:"id,attr"`
+	Data []graphMLData `xml:"data"`
+}
+
+type graphMLEdge struct {
+	Source string        `xml:"source,attr"`
+	Target string        `xml:"target,attr"`
+	Data   []graphMLData `xml:"data"`
+}
+
+type graphMLGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphMLNode `xml:"node"`
+	Edges       []graphMLEdge `xml:"edge"`
+}
+
+type graphMLDoc struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphMLKey `xml:"key"`
+	Graph   graphMLGraph `xml:"graph"`
+}
+
+func graphMLType(v interface{}) string {
+	switch v.(type) {
+	case bool:
+		return "boolean"
+	case int, int64:
+		return "long"
+	case float64:
+		return "double"
+	default:
+		return "string"
+	}
+}
+
+// graphMLDataFor turns props into data elements, declaring any keys not seen before in keys
+func graphMLDataFor(props map[string]interface{}, prefix string, kind string, keys map[string]graphMLKey) []graphMLData {
+	names := make([]string, 0, len(props))
+	for k := range props {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	data := []graphMLData{}
+	for _, k := range names {
+		id := prefix + k
+		if _, ok := keys[id]; !ok {
+			keys[id] = graphMLKey{id, kind, k, graphMLType(props[k])}
+		}
+		data = append(data, graphMLData{id, fmt.Sprint(props[k])})
+	}
+	return data
+}
+
+// WriteGraphML renders sg as GraphML.
+// Every vertex and edge property is kept as a data element, along with the vertex/edge label
+// (under "kind" and "label" respectively) and a short display label for vertices.
+func WriteGraphML(out io.Writer, sg *Subgraph) error {
+	keys := map[string]graphMLKey{
+		"kind":  {"kind", "node", "kind", "string"},
+		"label": {"label", "node", "label", "string"},
+		"edge":  {"edge", "edge", "label", "string"},
+	}
+
+	graph := graphMLGraph{ID: "G", EdgeDefault: "directed"}
+
+	for _, v := range sg.Vertices {
+		data := []graphMLData{
+			{"kind", v.Label()},
+			{"label", displayLabel(v)},
+		}
+		data = append(data, graphMLDataFor(v.Properties(), "v_", "node", keys)...)
+		graph.Nodes = append(graph.Nodes, graphMLNode{sg.ID(v), data})
+	}
+
+	for _, e := range sg.Edges {
+		data := []graphMLData{{"edge", e.Label}}
+		data = append(data, graphMLDataFor(e.Properties, "e_", "edge", keys)...)
+		graph.Edges = append(graph.Edges, graphMLEdge{sg.ID(e.Source), sg.ID(e.Target), data})
+	}
+
+	doc := graphMLDoc{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Graph: graph,
+	}
+
+	keyIDs := make([]string, 0, len(keys))
+	for id := range keys {
+		keyIDs = append(keyIDs, id)
+	}
+	sort.Strings(keyIDs)
+	for _, id := range keyIDs {
+		doc.Keys = append(doc.Keys, keys[id])
+	}
+
+	if _, err := io.WriteString(out, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(out)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, "\n")
+	return err
+}
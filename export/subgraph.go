@@ -0,0 +1,272 @@
+package export
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	gbackend "github.com/kallsyms/go-graph/backend"
+	"github.com/kallsyms/go-graph/schema"
+)
+
+// Subgraph is a small, self-contained piece of the stored graph, ready to be rendered.
+type Subgraph struct {
+	Root     schema.Vertex
+	Vertices []schema.Vertex
+	Edges    []schema.Edge
+
+	// vertices read back from a backend aren't necessarily the same struct each time, so key them by backend meta
+	ids   map[interface{}]string
+	edges map[string]bool
+}
+
+func newSubgraph(root schema.Vertex) *Subgraph {
+	sg := &Subgraph{
+		Root:  root,
+		ids:   map[interface{}]string{},
+		edges: map[string]bool{},
+	}
+	sg.addVertex(root)
+	return sg
+}
+
+// ID returns the node id of v in this subgraph, or "" if v isn't in it
+func (sg *Subgraph) ID(v schema.Vertex) string {
+	return sg.ids[v.GetBackendMeta()]
+}
+
+func (sg *Subgraph) addVertex(v schema.Vertex) {
+	if sg.ID(v) != "" {
+		return
+	}
+	sg.ids[v.GetBackendMeta()] = fmt.Sprintf("n%d", len(sg.Vertices))
+	sg.Vertices = append(sg.Vertices, v)
+}
+
+func (sg *Subgraph) addEdge(edge schema.Edge) {
+	sg.addVertex(edge.Source)
+	sg.addVertex(edge.Target)
+
+	key := sg.ID(edge.Source) + " " + edge.Label + " " + sg.ID(edge.Target)
+	// a select's cases can lead to the same statement
+	if selectCase, _ := edge.Properties["selectCase"].(string); selectCase != "" {
+		key += " " + selectCase
+	}
+	if sg.edges[key] {
+		return
+	}
+	sg.edges[key] = true
+	sg.Edges = append(sg.Edges, edge)
+}
+
+// FindFunction looks up the function called name in the package pkgPath.
+// name can be a full symbol, the end of one (e.g. "(*T).M"), or just the function name if that's unambiguous.
+// version may be empty if only one version of the package is in the backend.
+func FindFunction(backend gbackend.Backend, pkgPath, version, name string) (*schema.Function, error) {
+	pkgs, err := backend.GetPackages()
+	if err != nil {
+		return nil, err
+	}
+
+	var pkg *schema.Package
+	for _, p := range pkgs {
+		if p.SourceURL != pkgPath || (version != "" && p.Version != version) {
+			continue
+		}
+		if pkg != nil {
+			return nil, fmt.Errorf("Multiple versions of %q found (%s, %s), pick one", pkgPath, pkg.Version, p.Version)
+		}
+		pkg = p
+	}
+	if pkg == nil {
+		return nil, fmt.Errorf("Package %q not found", pkgPath)
+	}
+
+	functions, err := backend.PackageFunctions(pkg)
+	if err != nil {
+		return nil, err
+	}
+	if fn, ok := functions[name]; ok {
+		return fn, nil
+	}
+
+	// not a full symbol, so try the end of one (e.g. "(*T).M") or a bare function name
+	matches := []string{}
+	for symbol, f := range functions {
+		if f.Name == name || strings.HasSuffix(symbol, "."+name) {
+			matches = append(matches, symbol)
+		}
+	}
+	sort.Strings(matches)
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("Function %q not found in %s@%s", name, pkg.SourceURL, pkg.Version)
+	case 1:
+		return functions[matches[0]], nil
+	default:
+		return nil, fmt.Errorf("%q is ambiguous in %s@%s, pick one of: %s", name, pkg.SourceURL, pkg.Version, strings.Join(matches, ", "))
+	}
+}
+
+func targets(edges []schema.Edge) []schema.Vertex {
+	vs := make([]schema.Vertex, len(edges))
+	for i, e := range edges {
+		vs[i] = e.Target
+	}
+	return vs
+}
+
+func sources(edges []schema.Edge) []schema.Vertex {
+	vs := make([]schema.Vertex, len(edges))
+	for i, e := range edges {
+		vs[i] = e.Source
+	}
+	return vs
+}
+
+// FunctionNeighbourhood builds the subgraph made of fn's statement-level CFG, the functions it calls (linked from the
+// statements they're called from), and the callers and callees of fn up to depth calls away.
+// Calls are collapsed into a single "Calls" edge between caller and callee, skipping the functioncall vertex.
+func FunctionNeighbourhood(t gbackend.Traverser, fn *schema.Function, depth int) (*Subgraph, error) {
+	sg := newSubgraph(fn)
+
+	// CFG
+	stmtEdges, err := t.OutEdges(fn, "Statement")
+	if err != nil {
+		return nil, err
+	}
+	for _, stmt := range targets(stmtEdges) {
+		sg.addVertex(stmt)
+
+		next, err := t.OutEdges(stmt, "Next")
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range next {
+			sg.addEdge(e)
+		}
+	}
+
+	first, err := t.OutEdges(fn, "FirstStatement")
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range first {
+		sg.addEdge(e)
+	}
+
+	// calls made directly by fn hang off of the statement they're made from
+	calls, err := t.OutEdges(fn, "Calls")
+	if err != nil {
+		return nil, err
+	}
+	for _, fc := range targets(calls) {
+		callees, err := t.OutEdges(fc, "Callee")
+		if err != nil {
+			return nil, err
+		}
+		sites, err := t.OutEdges(fc, "CallSiteStatement")
+		if err != nil {
+			return nil, err
+		}
+
+		var from schema.Vertex = fn
+		if len(sites) > 0 {
+			from = sites[0].Target
+		}
+		for _, callee := range targets(callees) {
+			sg.addEdge(schema.Edge{Source: from, Label: "Calls", Target: callee})
+		}
+	}
+
+	// walk callees outwards...
+	// (tracking what's been walked separately from what's in sg, since fn's callees were added along with its CFG)
+	walked := map[interface{}]bool{fn.GetBackendMeta(): true}
+	frontier := []schema.Vertex{fn}
+	for i := 0; i < depth; i++ {
+		next := []schema.Vertex{}
+		for _, caller := range frontier {
+			calls, err := t.OutEdges(caller, "Calls")
+			if err != nil {
+				return nil, err
+			}
+			for _, fc := range targets(calls) {
+				callees, err := t.OutEdges(fc, "Callee")
+				if err != nil {
+					return nil, err
+				}
+				for _, callee := range targets(callees) {
+					sg.addVertex(callee)
+					if !walked[callee.GetBackendMeta()] {
+						walked[callee.GetBackendMeta()] = true
+						next = append(next, callee)
+					}
+					if caller != fn {
+						sg.addEdge(schema.Edge{Source: caller, Label: "Calls", Target: callee})
+					}
+				}
+			}
+		}
+		frontier = next
+	}
+
+	// ...and callers inwards
+	walked = map[interface{}]bool{fn.GetBackendMeta(): true}
+	frontier = []schema.Vertex{fn}
+	for i := 0; i < depth; i++ {
+		next := []schema.Vertex{}
+		for _, callee := range frontier {
+			callsites, err := t.InEdges(callee, "Callee")
+			if err != nil {
+				return nil, err
+			}
+			for _, fc := range sources(callsites) {
+				calls, err := t.InEdges(fc, "Calls")
+				if err != nil {
+					return nil, err
+				}
+				for _, caller := range sources(calls) {
+					sg.addVertex(caller)
+					if !walked[caller.GetBackendMeta()] {
+						walked[caller.GetBackendMeta()] = true
+						next = append(next, caller)
+					}
+					sg.addEdge(schema.Edge{Source: caller, Label: "Calls", Target: callee})
+				}
+			}
+		}
+		frontier = next
+	}
+
+	return sg, nil
+}
+
+// displayLabel is the short human readable text to show for v
+func displayLabel(v schema.Vertex) string {
+	switch v := v.(type) {
+	case *schema.Package:
+		return v.SourceURL + "@" + v.Version
+	case *schema.Function:
+		if strings.HasPrefix(v.Receiver, "*") {
+			return "(" + v.Receiver + ")." + v.Name
+		} else if v.Receiver != "" {
+			return v.Receiver + "." + v.Name
+		}
+		return v.Name
+	case *schema.Statement:
+		return v.Text
+	case *schema.Variable:
+		return v.Name + " " + v.Type
+	case *schema.Type:
+		return v.Symbol
+	case *schema.Field:
+		return v.Name + " " + v.Type
+	}
+	return v.Label()
+}
+
+func isBackEdge(e schema.Edge) bool {
+	b, _ := e.Properties["isBackEdge"].(bool)
+	return b
+}
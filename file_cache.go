@@ -11,6 +11,26 @@ import (
 
 type fileCache map[string][]byte
 
+func (cache fileCache) contents(name string) ([]byte, error) {
+	if contents, ok := cache[name]; ok {
+		return contents, nil
+	}
+
+	fh, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer fh.Close()
+
+	contents, err := io.ReadAll(fh)
+	if err != nil {
+		return nil, err
+	}
+
+	cache[name] = contents
+	return contents, nil
+}
+
 func (cache fileCache) readNodeSource(fset *token.FileSet, node ast.Node, limit int) (string, int, string) {
 	file := fset.File(node.Pos())
 	if file == nil {
@@ -28,23 +48,10 @@ func (cache fileCache) readNodeSource(fset *token.FileSet, node ast.Node, limit
 		end = file.Size()
 	}
 
-	var contents []byte
-	var ok bool
-	if contents, ok = cache[file.Name()]; !ok {
-		fh, err := os.Open(file.Name())
-		if err != nil {
-			logrus.Infof("Unable to open file %q to get source: %v", file.Name(), err)
-			return "", -1, ""
-		}
-		defer fh.Close()
-
-		contents, err = io.ReadAll(fh)
-		if err != nil {
-			logrus.Infof("Unable to read from file %q to get source: %v", end-start, file.Name(), err)
-			return "", -1, ""
-		}
-
-		cache[file.Name()] = contents
+	contents, err := cache.contents(file.Name())
+	if err != nil {
+		logrus.Infof("Unable to read file %q to get source: %v", file.Name(), err)
+		return "", -1, ""
 	}
 
 	size := end - start
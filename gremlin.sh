@@ -0,0 +1,3 @@
+#!/bin/sh
+# Local TinkerGraph-backed Gremlin Server for use with -db ws://localhost:8182
+exec docker run -d --restart always -p 8182:8182 tinkerpop/gremlin-server:3.5.2
@@ -1,14 +1,21 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"flag"
+	"fmt"
 	"go/ast"
+	"go/constant"
 	"go/token"
 	"go/types"
 	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
 	"runtime/pprof"
-	"sync"
+	"sort"
+	"strings"
 
 	"github.com/kallsyms/go-graph/coordination"
 	"github.com/kallsyms/go-graph/schema"
@@ -29,9 +36,11 @@ func createGraphVars(pkg *packages.Package) map[*types.Var]*schema.Variable {
 	for ident, typ := range pkg.TypesInfo.Defs {
 		switch typ := typ.(type) {
 		// TODO: tuple?
-		// TODO: const
 		case *types.Var:
-			// TODO: check typ.IsField since struct members are Vars
+			// struct members are Vars too, but get field vertices instead
+			if typ.IsField() {
+				continue
+			}
 			gVar := &schema.Variable{
 				Name: ident.Name,
 				Type: typ.Type().String(),
@@ -40,9 +49,556 @@ func createGraphVars(pkg *packages.Package) map[*types.Var]*schema.Variable {
 		}
 	}
 
+	// unnamed parameters and results aren't defined anywhere, but still get a vertex so the signature is complete
+	for _, root := range pkg.Syntax {
+		astutil.Apply(root, func(cur *astutil.Cursor) bool {
+			sig := funcSignature(cur.Node(), pkg)
+			if sig == nil {
+				return true
+			}
+
+			for _, tuple := range []*types.Tuple{sig.Params(), sig.Results()} {
+				for i := 0; i < tuple.Len(); i++ {
+					if v := tuple.At(i); graphVarMap[v] == nil {
+						graphVarMap[v] = &schema.Variable{
+							Name: v.Name(),
+							Type: v.Type().String(),
+						}
+					}
+				}
+			}
+			return true
+		}, nil)
+	}
+
 	return graphVarMap
 }
 
+// funcSignature returns the signature of a function declaration (with a body) or literal, nil for any other node
+func funcSignature(node ast.Node, pkg *packages.Package) *types.Signature {
+	switch node := node.(type) {
+	case *ast.FuncDecl:
+		if node.Body == nil {
+			return nil
+		}
+		if fn, ok := pkg.TypesInfo.Defs[node.Name].(*types.Func); ok {
+			return fn.Type().(*types.Signature)
+		}
+	case *ast.FuncLit:
+		if sig, ok := pkg.TypesInfo.TypeOf(node).(*types.Signature); ok {
+			return sig
+		}
+	}
+	return nil
+}
+
+func constantValue(c *types.Const) string {
+	switch c.Val().Kind() {
+	case constant.Float, constant.Complex:
+		return c.Val().String()
+	}
+	return c.Val().ExactString()
+}
+
+// createGraphConsts creates a constant vertex for every constant declared in pkg, package level or not
+func createGraphConsts(pkg *packages.Package) map[*types.Const]*schema.Constant {
+	graphConstMap := map[*types.Const]*schema.Constant{}
+	for ident, obj := range pkg.TypesInfo.Defs {
+		c, ok := obj.(*types.Const)
+		if !ok {
+			continue
+		}
+
+		gConst := &schema.Constant{
+			Name:  ident.Name,
+			Type:  c.Type().String(),
+			Value: constantValue(c),
+		}
+		if c.Parent() == pkg.Types.Scope() {
+			gConst.Symbol = pkg.PkgPath + "." + c.Name()
+		}
+		graphConstMap[c] = gConst
+	}
+
+	return graphConstMap
+}
+
+// moduleRoot returns the directory pkg's file paths are made relative to, and the module it's in
+func moduleRoot(pkg *packages.Package, pkgDir string) (string, string) {
+	if pkg.Module != nil && pkg.Module.Dir != "" {
+		return pkg.Module.Dir, pkg.Module.Path
+	}
+
+	goroot := filepath.Join(runtime.GOROOT(), "src")
+	if len(pkg.GoFiles) > 0 && strings.HasPrefix(pkg.GoFiles[0], goroot) {
+		return goroot, "std"
+	}
+
+	if abs, err := filepath.Abs(pkgDir); err == nil {
+		pkgDir = abs
+	}
+	return pkgDir, ""
+}
+
+// createGraphFiles creates a file vertex for every (parsed) source file of pkg, keyed by the absolute file name
+func createGraphFiles(pkg *packages.Package, pkgDir string, fCache fileCache) map[string]*schema.File {
+	root, module := moduleRoot(pkg, pkgDir)
+
+	graphFileMap := map[string]*schema.File{}
+	for _, syntax := range pkg.Syntax {
+		file := pkg.Fset.File(syntax.Pos())
+		if file == nil {
+			continue
+		}
+
+		path := file.Name()
+		if rel, err := filepath.Rel(root, path); err == nil {
+			path = rel
+		}
+
+		gFile := &schema.File{
+			Path:    filepath.ToSlash(path),
+			Package: pkg.PkgPath,
+			Module:  module,
+		}
+		if contents, err := fCache.contents(file.Name()); err == nil {
+			hash := sha256.Sum256(contents)
+			gFile.Hash = hex.EncodeToString(hash[:])
+		} else {
+			logrus.Infof("Unable to read file %q to hash it: %v", file.Name(), err)
+		}
+
+		graphFileMap[file.Name()] = gFile
+	}
+
+	return graphFileMap
+}
+
+// createGraphFields creates a field vertex for every field of every struct type (named or not) declared in pkg
+func createGraphFields(pkg *packages.Package) map[*types.Var]*schema.Field {
+	graphFieldMap := map[*types.Var]*schema.Field{}
+	for _, root := range pkg.Syntax {
+		astutil.Apply(root, func(cur *astutil.Cursor) bool {
+			structType, ok := cur.Node().(*ast.StructType)
+			if !ok {
+				return true
+			}
+
+			st, ok := pkg.TypesInfo.TypeOf(structType).(*types.Struct)
+			if !ok {
+				return true
+			}
+
+			for i := 0; i < st.NumFields(); i++ {
+				field := st.Field(i)
+				graphFieldMap[field] = &schema.Field{
+					Name:     field.Name(),
+					Type:     field.Type().String(),
+					Embedded: field.Embedded(),
+					Index:    i,
+					Tag:      st.Tag(i),
+				}
+			}
+
+			return true
+		}, nil)
+	}
+
+	return graphFieldMap
+}
+
+func typeKind(obj *types.TypeName) string {
+	if obj.IsAlias() {
+		return "alias"
+	}
+	return underlyingKind(obj.Type())
+}
+
+func underlyingKind(typ types.Type) string {
+	switch typ.Underlying().(type) {
+	case *types.Struct:
+		return "struct"
+	case *types.Interface:
+		return "interface"
+	case *types.Basic:
+		return "basic"
+	case *types.Pointer:
+		return "pointer"
+	case *types.Slice:
+		return "slice"
+	case *types.Array:
+		return "array"
+	case *types.Map:
+		return "map"
+	case *types.Chan:
+		return "chan"
+	case *types.Signature:
+		return "func"
+	}
+	return "unknown"
+}
+
+// packageTypeNames returns every package level named type (including aliases) declared in pkg
+func packageTypeNames(pkg *packages.Package) []*types.TypeName {
+	var typeNames []*types.TypeName
+	scope := pkg.Types.Scope()
+	for _, name := range scope.Names() {
+		if obj, ok := scope.Lookup(name).(*types.TypeName); ok {
+			typeNames = append(typeNames, obj)
+		}
+	}
+	return typeNames
+}
+
+// createGraphTypes creates a type vertex for every package level named type in pkg.
+// Types declared inside functions aren't included.
+func createGraphTypes(pkg *packages.Package) map[*types.TypeName]*schema.Type {
+	graphTypeMap := map[*types.TypeName]*schema.Type{}
+	for _, obj := range packageTypeNames(pkg) {
+		graphTypeMap[obj] = &schema.Type{
+			Name:       obj.Name(),
+			Symbol:     pkg.PkgPath + "." + obj.Name(),
+			Kind:       typeKind(obj),
+			Underlying: obj.Type().Underlying().String(),
+		}
+	}
+
+	return graphTypeMap
+}
+
+// hasTypeEdge links v to the named type of typ (looking through pointers), if that type is in the graph
+func hasTypeEdge(v schema.Vertex, typ types.Type, graphTypeMap map[*types.TypeName]*schema.Type) (schema.Edge, bool) {
+	isPointer := false
+	for {
+		ptr, ok := typ.(*types.Pointer)
+		if !ok {
+			break
+		}
+		typ = ptr.Elem()
+		isPointer = true
+	}
+
+	// *types.Named, or *types.Alias on toolchains which keep aliases around
+	named, ok := typ.(interface{ Obj() *types.TypeName })
+	if !ok {
+		return schema.Edge{}, false
+	}
+	gType, ok := graphTypeMap[named.Obj()]
+	if !ok {
+		return schema.Edge{}, false
+	}
+
+	return schema.Edge{
+		Source: v,
+		Label:  "HasType",
+		Target: gType,
+		Properties: map[string]interface{}{
+			"isPointer": isPointer,
+		},
+	}, true
+}
+
+// TYPE_ARGS_PACKAGE holds the type vertices of type arguments which aren't declared in any package (see typeArgTypes).
+// No real package has an empty version and this path.
+var TYPE_ARGS_PACKAGE = coordination.PackageTuple{Name: "builtin", Version: ""}
+
+// typeArgTypes creates type vertices for type arguments which have no vertex of their own (e.g. int, []byte or
+// func()), one for each distinct type. They're linked from TYPE_ARGS_PACKAGE by Types edges, so later ingests reuse
+// them instead of adding their own.
+type typeArgTypes struct {
+	backend  gbackend.Backend
+	fset     *token.FileSet
+	vertices chan schema.Vertex
+	// looked up on first use, since most ingests never need them
+	pkg   *schema.Package
+	types map[string]*schema.Type
+	edges []schema.Edge
+}
+
+// load finds (or creates) TYPE_ARGS_PACKAGE, and the types already in it
+func (argTypes *typeArgTypes) load() {
+	pkg, found, err := argTypes.backend.GetPackage(TYPE_ARGS_PACKAGE)
+	if err != nil {
+		logrus.Fatalf("Error looking up package %v: %v", TYPE_ARGS_PACKAGE, err)
+	}
+
+	argTypes.types = map[string]*schema.Type{}
+	if found {
+		argTypes.types, err = argTypes.backend.PackageTypes(pkg)
+		if err != nil {
+			logrus.Fatalf("Error retrieving types for package %v: %v", TYPE_ARGS_PACKAGE, err)
+		}
+	} else {
+		pkg, err = argTypes.backend.CreatePackage(TYPE_ARGS_PACKAGE)
+		if err != nil {
+			logrus.Fatalf("Error creating package %v: %v", TYPE_ARGS_PACKAGE, err)
+		}
+	}
+	argTypes.pkg = pkg
+}
+
+func (argTypes *typeArgTypes) get(typ types.Type) *schema.Type {
+	if argTypes.pkg == nil {
+		argTypes.load()
+	}
+
+	symbol := typeArgSymbol(typ, argTypes.fset)
+	if gType, ok := argTypes.types[symbol]; ok {
+		return gType
+	}
+
+	kind := underlyingKind(typ)
+	if _, ok := typ.(*types.Alias); ok {
+		kind = "alias"
+	}
+	gType := &schema.Type{
+		Name:       types.TypeString(typ, nil),
+		Symbol:     symbol,
+		Kind:       kind,
+		Underlying: typ.Underlying().String(),
+	}
+	argTypes.types[symbol] = gType
+	argTypes.vertices <- gType
+	argTypes.edges = append(argTypes.edges, schema.Edge{
+		Source: argTypes.pkg,
+		Label:  "Types",
+		Target: gType,
+	})
+	return gType
+}
+
+// typeArgSymbol is typ's type string, except that types declared inside functions (which would otherwise look just
+// like package level ones) get where they're declared appended, e.g. "[]example.com/pkg.T·file.go:12"
+func typeArgSymbol(typ types.Type, fset *token.FileSet) string {
+	symbol := types.TypeString(typ, nil)
+	seen := map[*types.TypeName]bool{}
+	for _, obj := range localTypeNames(typ, nil) {
+		if seen[obj] {
+			continue
+		}
+		seen[obj] = true
+		pos := fset.Position(obj.Pos())
+		symbol += fmt.Sprintf("·%s:%d", filepath.Base(pos.Filename), pos.Line)
+	}
+	return symbol
+}
+
+// localTypeNames appends the types declared inside functions which typ is made of to names, in the order they're
+// written in its type string. Named types aren't looked into, so there's no need to worry about recursive types.
+func localTypeNames(typ types.Type, names []*types.TypeName) []*types.TypeName {
+	tuple := func(t *types.Tuple) {
+		for i := 0; i < t.Len(); i++ {
+			names = localTypeNames(t.At(i).Type(), names)
+		}
+	}
+
+	switch t := typ.(type) {
+	case interface {
+		Obj() *types.TypeName
+		TypeArgs() *types.TypeList
+	}:
+		// *types.Named or *types.Alias
+		if obj := t.Obj(); obj.Pkg() != nil && obj.Parent() != obj.Pkg().Scope() {
+			names = append(names, obj)
+		}
+		for i := 0; i < t.TypeArgs().Len(); i++ {
+			names = localTypeNames(t.TypeArgs().At(i), names)
+		}
+	case *types.Pointer:
+		names = localTypeNames(t.Elem(), names)
+	case *types.Slice:
+		names = localTypeNames(t.Elem(), names)
+	case *types.Array:
+		names = localTypeNames(t.Elem(), names)
+	case *types.Chan:
+		names = localTypeNames(t.Elem(), names)
+	case *types.Map:
+		names = localTypeNames(t.Key(), names)
+		names = localTypeNames(t.Elem(), names)
+	case *types.Signature:
+		tuple(t.Params())
+		tuple(t.Results())
+	case *types.Struct:
+		for i := 0; i < t.NumFields(); i++ {
+			names = localTypeNames(t.Field(i).Type(), names)
+		}
+	case *types.Interface:
+		for i := 0; i < t.NumExplicitMethods(); i++ {
+			names = localTypeNames(t.ExplicitMethod(i).Type(), names)
+		}
+		for i := 0; i < t.NumEmbeddeds(); i++ {
+			names = localTypeNames(t.EmbeddedType(i), names)
+		}
+	case *types.Union:
+		for i := 0; i < t.Len(); i++ {
+			names = localTypeNames(t.Term(i).Type(), names)
+		}
+	}
+	return names
+}
+
+// typeArgVertex finds the vertex for a type argument: its named type (looking through pointers) or type parameter, or
+// a vertex from argTypes for anything else
+func typeArgVertex(typ types.Type, graphTypeMap map[*types.TypeName]*schema.Type, graphTypeParamMap map[*types.TypeParam]*schema.TypeParam, argTypes *typeArgTypes) schema.Vertex {
+	elem := typ
+	for {
+		ptr, ok := elem.(*types.Pointer)
+		if !ok {
+			break
+		}
+		elem = ptr.Elem()
+	}
+
+	if tp, ok := elem.(*types.TypeParam); ok {
+		if gTypeParam, ok := graphTypeParamMap[tp]; ok {
+			return gTypeParam
+		}
+	}
+	if named, ok := elem.(interface{ Obj() *types.TypeName }); ok {
+		if gType, ok := graphTypeMap[named.Obj()]; ok {
+			return gType
+		}
+	}
+	return argTypes.get(typ)
+}
+
+// instantiatesEdges links source to each of the type arguments generic is instantiated with
+func instantiatesEdges(source schema.Vertex, generic types.Object, typeArgs *types.TypeList, graphTypeMap map[*types.TypeName]*schema.Type, graphTypeParamMap map[*types.TypeParam]*schema.TypeParam, argTypes *typeArgTypes) []schema.Edge {
+	var edges []schema.Edge
+	for i := 0; i < typeArgs.Len(); i++ {
+		edges = append(edges, schema.Edge{
+			Source: source,
+			Label:  "Instantiates",
+			Target: typeArgVertex(typeArgs.At(i), graphTypeMap, graphTypeParamMap, argTypes),
+			Properties: map[string]interface{}{
+				"index":   i,
+				"generic": generic.Pkg().Path() + "." + generic.Name(),
+				"typeArg": types.TypeString(typeArgs.At(i), nil),
+			},
+		})
+	}
+	return edges
+}
+
+// instanceOf returns the generic function or type expr names, and the type arguments it's instantiated with there
+// (explicitly, e.g. F[int], or inferred)
+func instanceOf(expr ast.Expr, pkg *packages.Package) (types.Object, *types.TypeList, bool) {
+	switch e := expr.(type) {
+	case *ast.ParenExpr:
+		return instanceOf(e.X, pkg)
+	case *ast.IndexExpr:
+		return instanceOf(e.X, pkg)
+	case *ast.IndexListExpr:
+		return instanceOf(e.X, pkg)
+	case *ast.SelectorExpr:
+		return instanceOf(e.Sel, pkg)
+	case *ast.Ident:
+		if inst, ok := pkg.TypesInfo.Instances[e]; ok {
+			return pkg.TypesInfo.Uses[e], inst.TypeArgs, true
+		}
+	}
+	return nil, nil, false
+}
+
+// typeInstanceEdges links gStmt to the type arguments of every generic type instantiated under node, which is gStmt's
+// AST
+func typeInstanceEdges(gStmt *schema.Statement, node ast.Node, pkg *packages.Package, graphTypeMap map[*types.TypeName]*schema.Type, graphTypeParamMap map[*types.TypeParam]*schema.TypeParam, argTypes *typeArgTypes) []schema.Edge {
+	var edges []schema.Edge
+	astutil.Apply(node, func(cur *astutil.Cursor) bool {
+		switch n := cur.Node().(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.Ident:
+			if obj, typeArgs, ok := instanceOf(n, pkg); ok {
+				if _, isType := obj.(*types.TypeName); isType {
+					edges = append(edges, instantiatesEdges(gStmt, obj, typeArgs, graphTypeMap, graphTypeParamMap, argTypes)...)
+				}
+			}
+		}
+		return true
+	}, nil)
+	return edges
+}
+
+// declaredInstanceEdges links v, a variable or field, to the type arguments of its type if it's (a pointer to) an
+// instantiated generic type
+func declaredInstanceEdges(v schema.Vertex, typ types.Type, graphTypeMap map[*types.TypeName]*schema.Type, graphTypeParamMap map[*types.TypeParam]*schema.TypeParam, argTypes *typeArgTypes) []schema.Edge {
+	for {
+		ptr, ok := typ.(*types.Pointer)
+		if !ok {
+			break
+		}
+		typ = ptr.Elem()
+	}
+
+	named, ok := typ.(*types.Named)
+	if !ok || named.TypeArgs().Len() == 0 {
+		return nil
+	}
+	return instantiatesEdges(v, named.Origin().Obj(), named.TypeArgs(), graphTypeMap, graphTypeParamMap, argTypes)
+}
+
+// implementsEdges links each concrete named type to the (non-empty) interfaces it satisfies, either itself or through a
+// pointer to it. Only pairs with at least one side in a new package are returned, the others are already in the DB.
+func implementsEdges(graphTypeMap map[*types.TypeName]*schema.Type, pkgIsNew map[string]bool) []schema.Edge {
+	var concrete, ifaces []*types.TypeName
+	for obj := range graphTypeMap {
+		if obj.IsAlias() {
+			continue
+		}
+		// satisfaction is only defined for instantiated generic types
+		if named, ok := obj.Type().(*types.Named); ok && named.TypeParams().Len() > 0 {
+			continue
+		}
+		if iface, ok := obj.Type().Underlying().(*types.Interface); ok {
+			if iface.NumMethods() > 0 {
+				ifaces = append(ifaces, obj)
+			}
+		} else {
+			concrete = append(concrete, obj)
+		}
+	}
+
+	// only check each interface against the types which have (at least) its first method
+	byMethod := map[string][]*types.TypeName{}
+	for _, obj := range concrete {
+		mset := types.NewMethodSet(types.NewPointer(obj.Type()))
+		for i := 0; i < mset.Len(); i++ {
+			name := mset.At(i).Obj().Name()
+			byMethod[name] = append(byMethod[name], obj)
+		}
+	}
+
+	var edges []schema.Edge
+	for _, ifaceObj := range ifaces {
+		iface := ifaceObj.Type().Underlying().(*types.Interface)
+		for _, obj := range byMethod[iface.Method(0).Name()] {
+			if !pkgIsNew[obj.Pkg().Path()] && !pkgIsNew[ifaceObj.Pkg().Path()] {
+				continue
+			}
+
+			isPointer := false
+			if !types.Implements(obj.Type(), iface) {
+				if !types.Implements(types.NewPointer(obj.Type()), iface) {
+					continue
+				}
+				isPointer = true
+			}
+
+			edges = append(edges, schema.Edge{
+				Source: graphTypeMap[obj],
+				Label:  "Implements",
+				Target: graphTypeMap[ifaceObj],
+				Properties: map[string]interface{}{
+					"isPointer": isPointer,
+				},
+			})
+		}
+	}
+
+	return edges
+}
+
 type CFGBlockSet map[*cfg.Block]interface{}
 
 func CFGBlockSetFrom(l []*cfg.Block) CFGBlockSet {
@@ -110,6 +666,102 @@ func (set CFGBlockSet) Equals(s2 CFGBlockSet) bool {
 	return true
 }
 
+// selectCases finds which select case is taken going from each non-empty block to its (non-empty) successors, and
+// so has to be done before pruneEmpty, as case blocks are often empty.
+// cases[bb][succ] are the source texts of the cases' communication statements (or "default") leading from bb to succ.
+// A block in cases ends with a select's communications, so any of its successors not in cases[bb] can't be reached
+// (the select has no default, which cfg still makes a path for).
+func selectCases(funcCFG *cfg.CFG, graphFirstStmtMap map[*cfg.Block]*schema.Statement, fset *token.FileSet, fCache fileCache) map[*cfg.Block]map[*cfg.Block][]string {
+	// the select each case is in
+	selects := map[*ast.CommClause]*ast.SelectStmt{}
+	for _, bb := range funcCFG.Blocks {
+		if sel, ok := bb.Stmt.(*ast.SelectStmt); ok {
+			for _, clause := range sel.Body.List {
+				selects[clause.(*ast.CommClause)] = sel
+			}
+		}
+	}
+
+	// caseText returns the case taken by going into block, and false if block can't be reached
+	caseText := func(block *cfg.Block) (string, bool) {
+		clause, ok := block.Stmt.(*ast.CommClause)
+		if !ok {
+			return "", true
+		}
+		switch block.Kind {
+		case cfg.KindSelectCaseBody:
+			_, _, text := fCache.readNodeSource(fset, clause.Comm, 1024)
+			return text, true
+		case cfg.KindSelectAfterCase:
+			// going past the last case is what cfg does for the default, wherever it is in the select
+			var last *ast.CommClause
+			hasDefault := false
+			for _, cc := range selects[clause].Body.List {
+				if cc := cc.(*ast.CommClause); cc.Comm != nil {
+					last = cc
+				} else {
+					hasDefault = true
+				}
+			}
+			if clause == last {
+				if hasDefault {
+					return "default", true
+				}
+				return "", false
+			}
+		}
+		return "", true
+	}
+
+	type step struct {
+		block *cfg.Block
+		text  string
+	}
+
+	cases := map[*cfg.Block]map[*cfg.Block][]string{}
+	for _, bb := range funcCFG.Blocks {
+		if _, ok := graphFirstStmtMap[bb]; !ok {
+			continue
+		}
+
+		seen := map[step]bool{}
+		queue := []step{}
+		for _, succ := range bb.Succs {
+			queue = append(queue, step{succ, ""})
+		}
+		for len(queue) > 0 {
+			s := queue[0]
+			queue = queue[1:]
+			if seen[s] {
+				continue
+			}
+			seen[s] = true
+
+			if s.text == "" {
+				text, ok := caseText(s.block)
+				if !ok {
+					continue
+				}
+				s.text = text
+			}
+
+			if _, ok := graphFirstStmtMap[s.block]; ok {
+				if s.text != "" {
+					if cases[bb] == nil {
+						cases[bb] = map[*cfg.Block][]string{}
+					}
+					cases[bb][s.block] = append(cases[bb][s.block], s.text)
+				}
+				continue
+			}
+			for _, succ := range s.block.Succs {
+				queue = append(queue, step{succ, s.text})
+			}
+		}
+	}
+	return cases
+}
+
 // find and remove "empty" blocks in funcCFG.
 // some blocks in the cfg have no statements (acting just as a fallthrough)
 // but these are not useful at all for us.
@@ -225,19 +877,32 @@ func backEdges(funcCFG *cfg.CFG) map[*cfg.Block]CFGBlockSet {
 	return cfgBackEdges
 }
 
-func resolveIdents(node ast.Node, pkg *packages.Package, graphVarMap map[*types.Var]*schema.Variable) []*schema.Variable {
-	var vars []*schema.Variable
+// resolveIdents returns the variables and constants used (or defined) anywhere under node.
+// Struct fields are handled by fieldAccessEdges instead.
+func resolveIdents(node ast.Node, pkg *packages.Package, graphVarMap map[*types.Var]*schema.Variable, graphConstMap map[*types.Const]*schema.Constant) []schema.Vertex {
+	var vars []schema.Vertex
+
+	resolve := func(varType *types.Var) {
+		if gVar, ok := graphVarMap[varType]; ok {
+			vars = append(vars, gVar)
+		}
+	}
 
 	astutil.Apply(node, func(stmtCur *astutil.Cursor) bool {
+		// function literals' statements are their own
+		if lit, ok := stmtCur.Node().(*ast.FuncLit); ok && lit != node {
+			return false
+		}
+
 		if ident, ok := stmtCur.Node().(*ast.Ident); ok {
 			if varType, ok := pkg.TypesInfo.Defs[ident].(*types.Var); ok {
 				// This can happen in the case of `var X struct {...}` (e.g. https://sourcegraph.com/github.com/golang/go/-/blob/src/internal/cpu/cpu.go?L26:5#tab=references)
-				if graphVarMap[varType] != nil {
-					vars = append(vars, graphVarMap[varType])
-				}
+				resolve(varType)
 			} else if varType, ok := pkg.TypesInfo.Uses[ident].(*types.Var); ok {
-				if graphVarMap[varType] != nil {
-					vars = append(vars, graphVarMap[varType])
+				resolve(varType)
+			} else if constType, ok := pkg.TypesInfo.Uses[ident].(*types.Const); ok {
+				if gConst, ok := graphConstMap[constType]; ok {
+					vars = append(vars, gConst)
 				}
 			}
 		}
@@ -248,29 +913,590 @@ func resolveIdents(node ast.Node, pkg *packages.Package, graphVarMap map[*types.
 	return vars
 }
 
-// simple encapsulating struct used to match ssa instructions to ast statements by location in source
-type stmtWithLoc struct {
-	start token.Pos
-	end   token.Pos
-	gStmt *schema.Statement
+// resolveFields finds the fields selected under node, e.g. both conf and Timeout in s.conf.Timeout
+func resolveFields(node ast.Node, pkg *packages.Package, graphFieldMap map[*types.Var]*schema.Field) []schema.Vertex {
+	var fields []schema.Vertex
+
+	astutil.Apply(node, func(cur *astutil.Cursor) bool {
+		switch n := cur.Node().(type) {
+		case *ast.FuncLit:
+			// function literals' statements are their own
+			return n == node
+		case *ast.SelectorExpr:
+			if fieldType, ok := pkg.TypesInfo.Uses[n.Sel].(*types.Var); ok && fieldType.IsField() {
+				if gField, ok := graphFieldMap[fieldType]; ok {
+					fields = append(fields, gField)
+				}
+			}
+		}
+		return true
+	}, nil)
+
+	return fields
 }
 
-func processPackage(pkgDir string, backend gbackend.Backend) {
-	// Minimal load to check if this is already done and get imports
-	config := &packages.Config{
-		Mode: packages.NeedName | packages.NeedFiles | packages.NeedModule | packages.NeedImports |
-			packages.NeedDeps | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo,
-		Logf: func(format string, args ...interface{}) {
-			logrus.Tracef(format, args...)
-		},
-		Dir: pkgDir,
+// fieldAccessEdges links gStmt to every struct field read (ReadsField) or written (WritesField) under node, which is
+// gStmt's AST. Fields are written by being assigned to, incremented/decremented or set in a composite literal.
+// Compound assignments (+=, ++, ...) both read and write.
+func fieldAccessEdges(gStmt *schema.Statement, node ast.Node, pkg *packages.Package, graphFieldMap map[*types.Var]*schema.Field) []schema.Edge {
+	// selectors' field idents which are written, and whether they're also read
+	written := map[*ast.Ident]bool{}
+	selectors := map[*ast.Ident]*ast.SelectorExpr{}
+
+	writeTo := func(expr ast.Expr, alsoRead bool) {
+		if sel, ok := astutil.Unparen(expr).(*ast.SelectorExpr); ok {
+			written[sel.Sel] = alsoRead
+		}
 	}
 
-	pkgs, err := packages.Load(config, "./...")
-	if err != nil {
-		logrus.Errorf("Error loading %q: %v", pkgDir, err)
-		return
-	}
+	astutil.Apply(node, func(cur *astutil.Cursor) bool {
+		switch n := cur.Node().(type) {
+		case *ast.FuncLit:
+			// function literals' statements are their own
+			return n == node
+		case *ast.SelectorExpr:
+			selectors[n.Sel] = n
+		case *ast.AssignStmt:
+			for _, lhs := range n.Lhs {
+				writeTo(lhs, n.Tok != token.ASSIGN && n.Tok != token.DEFINE)
+			}
+		case *ast.IncDecStmt:
+			writeTo(n.X, true)
+		case *ast.CompositeLit:
+			for _, elt := range n.Elts {
+				if kv, ok := elt.(*ast.KeyValueExpr); ok {
+					if key, ok := kv.Key.(*ast.Ident); ok {
+						written[key] = false
+					}
+				}
+			}
+		}
+		return true
+	}, nil)
+
+	var edges []schema.Edge
+	astutil.Apply(node, func(cur *astutil.Cursor) bool {
+		if lit, ok := cur.Node().(*ast.FuncLit); ok && lit != node {
+			return false
+		}
+
+		ident, ok := cur.Node().(*ast.Ident)
+		if !ok {
+			return true
+		}
+		fieldType, ok := pkg.TypesInfo.Uses[ident].(*types.Var)
+		if !ok || !fieldType.IsField() {
+			return true
+		}
+		gField, ok := graphFieldMap[fieldType]
+		if !ok {
+			return true
+		}
+
+		// promoted fields are selected through (implicit) embedded fields
+		isPromoted := false
+		if sel, ok := selectors[ident]; ok {
+			if selection, ok := pkg.TypesInfo.Selections[sel]; ok {
+				isPromoted = len(selection.Index()) > 1
+			}
+		}
+		props := func() map[string]interface{} {
+			return map[string]interface{}{
+				"isPromoted": isPromoted,
+			}
+		}
+
+		alsoRead, isWrite := written[ident]
+		if isWrite {
+			edges = append(edges, schema.Edge{Source: gStmt, Label: "WritesField", Target: gField, Properties: props()})
+		}
+		if !isWrite || alsoRead {
+			edges = append(edges, schema.Edge{Source: gStmt, Label: "ReadsField", Target: gField, Properties: props()})
+		}
+		return true
+	}, nil)
+
+	return edges
+}
+
+// channelEdges links gStmt to the channels sent to (Sends), received from (Receives) or closed (Closes) under node,
+// which is gStmt's AST. isRange is whether node is a range statement's channel, which the loop receives from.
+func channelEdges(gStmt *schema.Statement, node ast.Node, isRange bool, pkg *packages.Package, graphVarMap map[*types.Var]*schema.Variable, graphFieldMap map[*types.Var]*schema.Field) []schema.Edge {
+	var edges []schema.Edge
+	link := func(label string, ch ast.Expr) {
+		if gChan := exprVertex(ch, pkg, graphVarMap, graphFieldMap); gChan != nil {
+			edges = append(edges, schema.Edge{Source: gStmt, Label: label, Target: gChan})
+		}
+	}
+
+	if ch, ok := node.(ast.Expr); ok && isRange {
+		link("Receives", ch)
+	}
+
+	astutil.Apply(node, func(cur *astutil.Cursor) bool {
+		switch n := cur.Node().(type) {
+		case *ast.FuncLit:
+			// function literals' statements are their own
+			return n == node
+		case *ast.SendStmt:
+			link("Sends", n.Chan)
+		case *ast.UnaryExpr:
+			if n.Op == token.ARROW {
+				link("Receives", n.X)
+			}
+		case *ast.CallExpr:
+			if ident, ok := astutil.Unparen(n.Fun).(*ast.Ident); ok && len(n.Args) == 1 {
+				if builtin, ok := pkg.TypesInfo.Uses[ident].(*types.Builtin); ok && builtin.Name() == "close" {
+					link("Closes", n.Args[0])
+				}
+			}
+		}
+		return true
+	}, nil)
+
+	return edges
+}
+
+// newGraphFunc creates the function vertex for funcDecl.
+// numbered tracks how many init functions, and _ functions or methods (per receiver), have been seen so far in pkg,
+// since those can be declared more than once and so get numbered to keep Symbol unique.
+func newGraphFunc(funcDecl *ast.FuncDecl, pkg *packages.Package, numbered map[string]int) *schema.Function {
+	gFunc := &schema.Function{
+		Name: funcDecl.Name.String(),
+	}
+
+	symbol := gFunc.Name
+	if fn, ok := pkg.TypesInfo.Defs[funcDecl.Name].(*types.Func); ok {
+		sig := fn.Type().(*types.Signature)
+		gFunc.Signature = types.TypeString(sig, types.RelativeTo(pkg.Types))
+
+		if recv := sig.Recv(); recv != nil {
+			gFunc.Receiver = types.TypeString(recv.Type(), types.RelativeTo(pkg.Types))
+
+			// like pkg.(*T).M in stack traces, without any type parameters
+			recvType := recv.Type()
+			ptr := ""
+			if p, ok := recvType.(*types.Pointer); ok {
+				recvType = p.Elem()
+				ptr = "*"
+			}
+			recvName := gFunc.Receiver
+			if named, ok := recvType.(*types.Named); ok {
+				recvName = named.Obj().Name()
+			}
+			if ptr != "" {
+				symbol = "(*" + recvName + ")." + symbol
+			} else {
+				symbol = recvName + "." + symbol
+			}
+		}
+	}
+
+	if (gFunc.Receiver == "" && gFunc.Name == "init") || gFunc.Name == "_" {
+		n := numbered[symbol]
+		numbered[symbol]++
+		symbol = fmt.Sprintf("%s.%d", symbol, n)
+	}
+
+	gFunc.Symbol = pkg.PkgPath + "." + symbol
+	if funcDecl.Doc != nil {
+		gFunc.Doc = funcDecl.Doc.Text()
+	}
+	setFuncSpan(gFunc, funcDecl, pkg.Fset)
+	return gFunc
+}
+
+func setFuncSpan(gFunc *schema.Function, node ast.Node, fset *token.FileSet) {
+	// unadjusted, like statements
+	start := fset.PositionFor(node.Pos(), false)
+	end := fset.PositionFor(node.End(), false)
+	gFunc.StartLine, gFunc.StartColumn = start.Line, start.Column
+	gFunc.EndLine, gFunc.EndColumn = end.Line, end.Column
+}
+
+// createGraphDirectives finds the //go: directives on each function declaration in pkg: those in its doc comment, and
+// //go:linkname ones anywhere else in the package naming it (which the compiler allows, e.g. at the end of a file)
+func createGraphDirectives(pkg *packages.Package) map[*ast.FuncDecl][]*schema.Directive {
+	parse := func(comment *ast.Comment) (*schema.Directive, bool) {
+		if !strings.HasPrefix(comment.Text, "//go:") {
+			return nil, false
+		}
+		name, args := strings.TrimPrefix(comment.Text, "//go:"), ""
+		if i := strings.IndexAny(name, " \t"); i >= 0 {
+			name, args = name[:i], strings.TrimSpace(name[i:])
+		}
+		return &schema.Directive{Name: name, Args: args}, true
+	}
+
+	directives := map[*ast.FuncDecl][]*schema.Directive{}
+	inDoc := map[*ast.Comment]bool{}
+	funcsByName := map[string]*ast.FuncDecl{}
+	for _, root := range pkg.Syntax {
+		for _, decl := range root.Decls {
+			funcDecl, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			if funcDecl.Recv == nil {
+				funcsByName[funcDecl.Name.Name] = funcDecl
+			}
+			if funcDecl.Doc == nil {
+				continue
+			}
+			for _, comment := range funcDecl.Doc.List {
+				if d, ok := parse(comment); ok {
+					directives[funcDecl] = append(directives[funcDecl], d)
+					inDoc[comment] = true
+				}
+			}
+		}
+	}
+
+	for _, root := range pkg.Syntax {
+		for _, group := range root.Comments {
+			for _, comment := range group.List {
+				if inDoc[comment] {
+					continue
+				}
+				d, ok := parse(comment)
+				if !ok || d.Name != "linkname" {
+					continue
+				}
+				// //go:linkname localname [importpath.name]
+				args := strings.Fields(d.Args)
+				if len(args) == 0 {
+					continue
+				}
+				if funcDecl, ok := funcsByName[args[0]]; ok {
+					directives[funcDecl] = append(directives[funcDecl], d)
+				}
+			}
+		}
+	}
+
+	return directives
+}
+
+// newGraphFuncLit creates the function vertex for a function literal, named symbol
+func newGraphFuncLit(lit *ast.FuncLit, pkg *packages.Package, symbol string) *schema.Function {
+	gFunc := &schema.Function{
+		// e.g. main.func1, like in stack traces
+		Name:   strings.TrimPrefix(symbol, pkg.PkgPath+"."),
+		Symbol: symbol,
+	}
+	if sig, ok := pkg.TypesInfo.TypeOf(lit).(*types.Signature); ok {
+		gFunc.Signature = types.TypeString(sig, types.RelativeTo(pkg.Types))
+	}
+	setFuncSpan(gFunc, lit, pkg.Fset)
+	return gFunc
+}
+
+// a function declaration or literal, along with its function vertex
+type graphFuncNode struct {
+	// *ast.FuncDecl or *ast.FuncLit
+	node ast.Node
+	// nil for declarations without a body
+	body  *ast.BlockStmt
+	gFunc *schema.Function
+}
+
+// createGraphFuncs creates a function vertex for every function declaration and function literal in pkg, in source
+// order, so a literal always comes after the function it's in. Declarations without a body (implemented in assembly, or
+// pulled in with //go:linkname) are included too, but have no statements.
+// Literals are named like the compiler does: F.func1, F.func2, F.func1.1 for one inside F.func1, and glob..func1 for
+// ones outside of any function.
+func createGraphFuncs(pkg *packages.Package) []graphFuncNode {
+	var funcs []graphFuncNode
+	numbered := map[string]int{}
+
+	var addLits func(node ast.Node, prefix string)
+	addLits = func(node ast.Node, prefix string) {
+		n := 0
+		astutil.Apply(node, func(cur *astutil.Cursor) bool {
+			lit, ok := cur.Node().(*ast.FuncLit)
+			if !ok {
+				return true
+			}
+
+			n++
+			gFunc := newGraphFuncLit(lit, pkg, fmt.Sprintf("%s%d", prefix, n))
+			funcs = append(funcs, graphFuncNode{lit, lit.Body, gFunc})
+			addLits(lit.Body, gFunc.Symbol+".")
+			return false
+		}, nil)
+	}
+
+	globLits := 0
+	for _, root := range pkg.Syntax {
+		astutil.Apply(root, func(cur *astutil.Cursor) bool {
+			switch node := cur.Node().(type) {
+			case *ast.FuncDecl:
+				gFunc := newGraphFunc(node, pkg, numbered)
+				funcs = append(funcs, graphFuncNode{node, node.Body, gFunc})
+				if node.Body != nil {
+					addLits(node.Body, gFunc.Symbol+".func")
+				}
+				return false
+			case *ast.FuncLit:
+				// e.g. in a package level var initializer
+				globLits++
+				gFunc := newGraphFuncLit(node, pkg, fmt.Sprintf("%s.glob..func%d", pkg.PkgPath, globLits))
+				funcs = append(funcs, graphFuncNode{node, node.Body, gFunc})
+				addLits(node.Body, gFunc.Symbol+".")
+				return false
+			}
+			return true
+		}, nil)
+	}
+
+	// anything else with the same Symbol would be linked to the wrong function when pkg is seen again
+	seen := map[string]bool{}
+	for _, fn := range funcs {
+		if seen[fn.gFunc.Symbol] {
+			logrus.Warnf("More than one function in %s has symbol %q", pkg.PkgPath, fn.gFunc.Symbol)
+		}
+		seen[fn.gFunc.Symbol] = true
+	}
+
+	return funcs
+}
+
+// capturedVars returns the local variables declared outside of lit which it uses
+func capturedVars(lit *ast.FuncLit, pkg *packages.Package, graphVarMap map[*types.Var]*schema.Variable) []*schema.Variable {
+	var captured []*schema.Variable
+	seen := map[*types.Var]bool{}
+
+	astutil.Apply(lit.Body, func(cur *astutil.Cursor) bool {
+		ident, ok := cur.Node().(*ast.Ident)
+		if !ok {
+			return true
+		}
+
+		varType, ok := pkg.TypesInfo.Uses[ident].(*types.Var)
+		if !ok || varType.IsField() || varType.Pkg() == nil || varType.Parent() == varType.Pkg().Scope() || seen[varType] {
+			return true
+		}
+		if lit.Pos() <= varType.Pos() && varType.Pos() < lit.End() {
+			return true
+		}
+
+		seen[varType] = true
+		if gVar, ok := graphVarMap[varType]; ok {
+			captured = append(captured, gVar)
+		}
+		return true
+	}, nil)
+
+	return captured
+}
+
+// exprVertex returns the variable or field expr directly names (e.g. `x`, `s.f` or `pkg.X`), nil if it names neither
+// (e.g. `_`, `xs[i]` or `f()`) or it isn't in the graph.
+func exprVertex(expr ast.Expr, pkg *packages.Package, graphVarMap map[*types.Var]*schema.Variable, graphFieldMap map[*types.Var]*schema.Field) schema.Vertex {
+	var ident *ast.Ident
+	switch expr := astutil.Unparen(expr).(type) {
+	case *ast.Ident:
+		if expr.Name == "_" {
+			return nil
+		}
+		ident = expr
+	case *ast.SelectorExpr:
+		ident = expr.Sel
+	default:
+		return nil
+	}
+
+	obj := pkg.TypesInfo.Defs[ident]
+	if obj == nil {
+		obj = pkg.TypesInfo.Uses[ident]
+	}
+	varType, ok := obj.(*types.Var)
+	if !ok {
+		return nil
+	}
+	if varType.IsField() {
+		if gField, ok := graphFieldMap[varType]; ok {
+			return gField
+		}
+	} else if gVar, ok := graphVarMap[varType]; ok {
+		return gVar
+	}
+	return nil
+}
+
+// callSite is what's passed to a call, and what receives its results
+type callSite struct {
+	// the variables, constants and fields used in each argument
+	args [][]schema.Vertex
+	// the variable or field each result is assigned to, nil if it isn't (e.g. it's passed straight to another call)
+	results []schema.Vertex
+	// the generic function called, and its type arguments, if it's instantiated
+	generic  types.Object
+	typeArgs *types.TypeList
+}
+
+// createCallSites resolves the arguments and results of every call in pkg. They're keyed by the position ssa reports
+// for the call: the opening paren, or the go/defer keyword.
+func createCallSites(pkg *packages.Package, graphVarMap map[*types.Var]*schema.Variable, graphFieldMap map[*types.Var]*schema.Field, graphConstMap map[*types.Const]*schema.Constant) map[token.Pos]*callSite {
+	sites := map[token.Pos]*callSite{}
+	siteOf := func(call *ast.CallExpr) *callSite {
+		site, ok := sites[call.Lparen]
+		if !ok {
+			site = &callSite{}
+			site.generic, site.typeArgs, _ = instanceOf(call.Fun, pkg)
+			for _, arg := range call.Args {
+				vs := resolveIdents(arg, pkg, graphVarMap, graphConstMap)
+				site.args = append(site.args, append(vs, resolveFields(arg, pkg, graphFieldMap)...))
+			}
+			sites[call.Lparen] = site
+		}
+		return site
+	}
+
+	resultTarget := func(expr ast.Expr) schema.Vertex {
+		return exprVertex(expr, pkg, graphVarMap, graphFieldMap)
+	}
+
+	// a, b := f() or a, b := f(), g()
+	assign := func(lhs []ast.Expr, rhs []ast.Expr) {
+		if len(rhs) == 1 && len(lhs) > 1 {
+			if call, ok := astutil.Unparen(rhs[0]).(*ast.CallExpr); ok {
+				site := siteOf(call)
+				for _, expr := range lhs {
+					site.results = append(site.results, resultTarget(expr))
+				}
+			}
+			return
+		}
+
+		for i, expr := range rhs {
+			if call, ok := astutil.Unparen(expr).(*ast.CallExpr); ok && i < len(lhs) {
+				site := siteOf(call)
+				site.results = []schema.Vertex{resultTarget(lhs[i])}
+			}
+		}
+	}
+
+	for _, root := range pkg.Syntax {
+		astutil.Apply(root, func(cur *astutil.Cursor) bool {
+			switch node := cur.Node().(type) {
+			case *ast.CallExpr:
+				siteOf(node)
+			case *ast.GoStmt:
+				sites[node.Go] = siteOf(node.Call)
+			case *ast.DeferStmt:
+				sites[node.Defer] = siteOf(node.Call)
+			case *ast.AssignStmt:
+				assign(node.Lhs, node.Rhs)
+			case *ast.ValueSpec:
+				lhs := make([]ast.Expr, len(node.Names))
+				for i, name := range node.Names {
+					lhs[i] = name
+				}
+				assign(lhs, node.Values)
+			}
+			return true
+		}, nil)
+	}
+
+	return sites
+}
+
+// simple encapsulating struct used to match ssa instructions to ast statements by location in source
+type stmtWithLoc struct {
+	start token.Pos
+	end   token.Pos
+	gStmt *schema.Statement
+}
+
+func newGraphStatement(node ast.Node, fset *token.FileSet, fCache fileCache) *schema.Statement {
+	// statement isn't really an apt name, since at this point things like if conditional exprs
+	// have already been broken out
+	file, offset, text := fCache.readNodeSource(fset, node /*limit=*/, 1024)
+	// unadjusted, so //line directives don't point these somewhere other than File
+	start := fset.PositionFor(node.Pos(), false)
+	end := fset.PositionFor(node.End(), false)
+	return &schema.Statement{
+		File:        file,
+		Offset:      offset,
+		StartLine:   start.Line,
+		StartColumn: start.Column,
+		EndLine:     end.Line,
+		EndColumn:   end.Column,
+		Text:        text,
+		ASTType:     reflect.TypeOf(node).Elem().Name(),
+	}
+}
+
+// statementEdges links gStmt to the variables and constants it references, the variables it assigns, and the fields it
+// reads or writes. node is gStmt's AST.
+func statementEdges(gStmt *schema.Statement, node ast.Node, pkg *packages.Package, graphVarMap map[*types.Var]*schema.Variable, graphFieldMap map[*types.Var]*schema.Field, graphConstMap map[*types.Const]*schema.Constant) []schema.Edge {
+	// Find all variables that anything under this stmt references (or defines)
+	refGVars := resolveIdents(node, pkg, graphVarMap, graphConstMap)
+
+	// And all vars that this stmt may assign
+	var assignGVars []schema.Vertex
+	astutil.Apply(node, func(stmtCur *astutil.Cursor) bool {
+		switch node := stmtCur.Node().(type) {
+		case *ast.FuncLit:
+			return false
+		case *ast.AssignStmt:
+			// a := 1 or a = 1
+			for _, child := range node.Lhs {
+				assignGVars = append(assignGVars, resolveIdents(child, pkg, graphVarMap, graphConstMap)...)
+			}
+		case *ast.ValueSpec:
+			// var a int = 1 (const declarations aren't part of the CFG)
+			for _, child := range node.Names {
+				switch def := pkg.TypesInfo.Defs[child].(type) {
+				case *types.Var:
+					if gVar, ok := graphVarMap[def]; ok {
+						assignGVars = append(assignGVars, gVar)
+					}
+				}
+			}
+		}
+		return true
+	}, nil)
+
+	edges := fieldAccessEdges(gStmt, node, pkg, graphFieldMap)
+
+	for _, refGvar := range refGVars {
+		edges = append(edges, schema.Edge{
+			Source: gStmt,
+			Label:  "References",
+			Target: refGvar,
+		})
+	}
+
+	for _, assignGvar := range assignGVars {
+		edges = append(edges, schema.Edge{
+			Source: gStmt,
+			Label:  "Assigns",
+			Target: assignGvar,
+		})
+	}
+
+	return edges
+}
+
+func processPackage(pkgDir string, backend gbackend.Backend) {
+	// Minimal load to check if this is already done and get imports
+	config := &packages.Config{
+		Mode: packages.NeedName | packages.NeedFiles | packages.NeedModule | packages.NeedImports |
+			packages.NeedDeps | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo,
+		Logf: func(format string, args ...interface{}) {
+			logrus.Tracef(format, args...)
+		},
+		Dir:  pkgDir,
+		Fset: token.NewFileSet(),
+	}
+
+	pkgs, err := packages.Load(config, "./...")
+	if err != nil {
+		logrus.Errorf("Error loading %q: %v", pkgDir, err)
+		return
+	}
 
 	logrus.Infof("Processing %q", pkgDir)
 
@@ -278,15 +1504,15 @@ func processPackage(pkgDir string, backend gbackend.Backend) {
 
 	vertices := make(chan schema.Vertex, 32768)
 	verticesCount := 0
-	var vertexWG *sync.WaitGroup
+	var vertexWorkers *gbackend.VertexStream
 
 	if noProgressBar {
-		vertexWG = backend.AddVStream(vertices, func(done []schema.Vertex) {
+		vertexWorkers = backend.AddVStream(vertices, func(done []schema.Vertex) {
 			verticesCount += len(done)
 		})
 	} else {
 		progress := progressbar.Default(-1)
-		vertexWG = backend.AddVStream(vertices, func(done []schema.Vertex) {
+		vertexWorkers = backend.AddVStream(vertices, func(done []schema.Vertex) {
 			verticesCount += len(done)
 			progress.Add(len(done))
 		})
@@ -298,11 +1524,22 @@ func processPackage(pkgDir string, backend gbackend.Backend) {
 
 	// maps that will be built up during package walking, and used in callgraph processing
 	pkgIsNew := map[string]bool{}
-	pkgGraphStatements := map[string][]stmtWithLoc{}
-	graphFuncMap := map[*ast.FuncDecl]*schema.Function{}
+	graphPkgMap := map[string]*schema.Package{}
+	funcGraphStatements := map[*schema.Function][]stmtWithLoc{}
+	// package level var initializers, keyed by package path
+	initGraphStatements := map[string][]stmtWithLoc{}
+	// keyed by *ast.FuncDecl or *ast.FuncLit
+	graphFuncMap := map[ast.Node]*schema.Function{}
+	graphTypeMap := map[*types.TypeName]*schema.Type{}
+	graphFieldMap := map[*types.Var]*schema.Field{}
+	graphConstMap := map[*types.Const]*schema.Constant{}
+	graphTypeParamMap := map[*types.TypeParam]*schema.TypeParam{}
+	argTypes := &typeArgTypes{backend: backend, fset: config.Fset, vertices: vertices}
+	callSites := map[token.Pos]*callSite{}
 
 	// GlobalDebug allows us to go from ssa function to ast funcdecl
-	ssaProg := ssa.NewProgram(token.NewFileSet(), ssa.GlobalDebug)
+	// instantiate generic functions, so calls made through them resolve to concrete callees
+	ssaProg := ssa.NewProgram(token.NewFileSet(), ssa.GlobalDebug|ssa.InstantiateGenerics)
 
 	// Visit over the import tree like ssautil.Packages does
 	// Do it ourselves though, so we can get a handle to the packages.Package and the more detailed module information
@@ -314,7 +1551,10 @@ func processPackage(pkgDir string, backend gbackend.Backend) {
 
 		tup := coordination.NewPackageTuple(pkg, fallbackVersion)
 
-		graphPkg, found := backend.GetPackage(tup)
+		graphPkg, found, err := backend.GetPackage(tup)
+		if err != nil {
+			logrus.Fatalf("Error looking up package %v: %v", tup, err)
+		}
 		if !found {
 			graphPkg, err = backend.CreatePackage(tup)
 			if err != nil {
@@ -322,6 +1562,7 @@ func processPackage(pkgDir string, backend gbackend.Backend) {
 			}
 		}
 		pkgIsNew[pkg.PkgPath] = !found
+		graphPkgMap[pkg.PkgPath] = graphPkg
 
 		// Create the package in the SSA program.
 		// We'll use the results of this later to determine who calls what.
@@ -329,203 +1570,545 @@ func processPackage(pkgDir string, backend gbackend.Backend) {
 		ssaProg.CreatePackage(pkg.Types, pkg.Syntax, pkg.TypesInfo, true)
 
 		if found {
-			// populate graphFuncMap with the functions already in DB
-			alreadyPresentFuncs, err := backend.PackageFunctions(graphPkg)
+			// populate graphTypeMap, graphFieldMap, graphConstMap and graphFuncMap with the types, fields, constants and
+			// functions already in DB
+			alreadyPresentTypes, err := backend.PackageTypes(graphPkg)
 			if err != nil {
-				logrus.Errorf("Error retrieving functions for package %v", tup)
+				logrus.Errorf("Error retrieving types for package %v", tup)
 				return
 			}
 
-			for _, root := range pkg.Syntax {
-				astutil.Apply(root, func(cur *astutil.Cursor) bool {
-					funcDecl, ok := cur.Node().(*ast.FuncDecl)
-					if !ok {
-						return true
-					}
+			for _, obj := range packageTypeNames(pkg) {
+				symbol := pkg.PkgPath + "." + obj.Name()
+				gt, ok := alreadyPresentTypes[symbol]
+				if !ok {
+					logrus.Warnf("Type %q not found in DB despite existing package?", symbol)
+					continue
+				}
+				graphTypeMap[obj] = gt
+			}
 
-					if funcDecl.Body == nil {
-						return false
-					}
+			alreadyPresentFields, err := backend.PackageFields(graphPkg)
+			if err != nil {
+				logrus.Errorf("Error retrieving fields for package %v", tup)
+				return
+			}
 
-					gf, ok := alreadyPresentFuncs[funcDecl.Name.String()]
+			for _, obj := range packageTypeNames(pkg) {
+				st, ok := obj.Type().Underlying().(*types.Struct)
+				if !ok || obj.IsAlias() {
+					continue
+				}
+				for i := 0; i < st.NumFields(); i++ {
+					key := pkg.PkgPath + "." + obj.Name() + "." + st.Field(i).Name()
+					gf, ok := alreadyPresentFields[key]
 					if !ok {
-						logrus.Warnf("AST function %q (in %q) not found in DB despite existing package?", funcDecl.Name.String(), pkg.PkgPath)
-						return false
+						logrus.Warnf("Field %q not found in DB despite existing package?", key)
+						continue
 					}
+					graphFieldMap[st.Field(i)] = gf
+				}
+			}
+
+			alreadyPresentConsts, err := backend.PackageConstants(graphPkg)
+			if err != nil {
+				logrus.Errorf("Error retrieving constants for package %v", tup)
+				return
+			}
+
+			scope := pkg.Types.Scope()
+			for _, name := range scope.Names() {
+				c, ok := scope.Lookup(name).(*types.Const)
+				if !ok {
+					continue
+				}
+				gc, ok := alreadyPresentConsts[pkg.PkgPath+"."+name]
+				if !ok {
+					logrus.Warnf("Constant %q not found in DB despite existing package?", pkg.PkgPath+"."+name)
+					continue
+				}
+				graphConstMap[c] = gc
+			}
 
-					gf.Package = graphPkg
-					graphFuncMap[funcDecl] = gf
+			alreadyPresentFuncs, err := backend.PackageFunctions(graphPkg)
+			if err != nil {
+				logrus.Errorf("Error retrieving functions for package %v: %v", tup, err)
+				return
+			}
+
+			for _, fn := range createGraphFuncs(pkg) {
+				gf, ok := alreadyPresentFuncs[fn.gFunc.Symbol]
+				if !ok {
+					logrus.Warnf("AST function %q not found in DB despite existing package?", fn.gFunc.Symbol)
+					continue
+				}
 
-					return false
-				}, nil)
+				gf.Package = graphPkg
+				graphFuncMap[fn.node] = gf
 			}
 
 			// done with this pkg now
 			return
 		}
 
+		// dependencies are visited first, so this links to the exact versions pkg was built against
+		importPaths := make([]string, 0, len(pkg.Imports))
+		for importPath := range pkg.Imports {
+			importPaths = append(importPaths, importPath)
+		}
+		sort.Strings(importPaths)
+		for _, importPath := range importPaths {
+			if gImport, ok := graphPkgMap[pkg.Imports[importPath].PkgPath]; ok {
+				edges = append(edges, schema.Edge{
+					Source: graphPkg,
+					Label:  "Imports",
+					Target: gImport,
+				})
+			}
+		}
+
 		logrus.Debugf("Processing new package %q", pkg.PkgPath)
 
+		graphFileMap := createGraphFiles(pkg, pkgDir, fCache)
+		for _, gFile := range graphFileMap {
+			vertices <- gFile
+			edges = append(edges, schema.Edge{
+				Source: graphPkg,
+				Label:  "Files",
+				Target: gFile,
+			})
+		}
+
 		graphVarMap := createGraphVars(pkg)
 		for _, gVar := range graphVarMap {
 			vertices <- gVar
 		}
 
-		// Extract all function declarations from the package
+		pkgGraphConsts := createGraphConsts(pkg)
+		for c, gConst := range pkgGraphConsts {
+			graphConstMap[c] = gConst
+			vertices <- gConst
+			if gConst.Symbol != "" {
+				edges = append(edges, schema.Edge{
+					Source: graphPkg,
+					Label:  "Constants",
+					Target: gConst,
+				})
+			}
+		}
+
+		pkgGraphFields := createGraphFields(pkg)
+		for field, gField := range pkgGraphFields {
+			graphFieldMap[field] = gField
+			vertices <- gField
+		}
+
+		pkgGraphTypes := createGraphTypes(pkg)
+		for obj, gType := range pkgGraphTypes {
+			graphTypeMap[obj] = gType
+			vertices <- gType
+			edges = append(edges, schema.Edge{
+				Source: graphPkg,
+				Label:  "Types",
+				Target: gType,
+			})
+
+			if st, ok := obj.Type().Underlying().(*types.Struct); ok && !obj.IsAlias() {
+				for i := 0; i < st.NumFields(); i++ {
+					if gField, ok := graphFieldMap[st.Field(i)]; ok {
+						edges = append(edges, schema.Edge{
+							Source: gType,
+							Label:  "Fields",
+							Target: gField,
+						})
+					}
+				}
+			}
+		}
+
+		// type parameters, linked to their constraint if it's a named type
+		addTypeParams := func(owner schema.Vertex, lists ...*types.TypeParamList) {
+			index := 0
+			for _, list := range lists {
+				for i := 0; i < list.Len(); i++ {
+					tp := list.At(i)
+					gTypeParam := &schema.TypeParam{
+						Name:       tp.Obj().Name(),
+						Constraint: types.TypeString(tp.Constraint(), types.RelativeTo(pkg.Types)),
+					}
+					graphTypeParamMap[tp] = gTypeParam
+					vertices <- gTypeParam
+					edges = append(edges, schema.Edge{
+						Source: owner,
+						Label:  "TypeParams",
+						Target: gTypeParam,
+						Properties: map[string]interface{}{
+							"index": index,
+						},
+					})
+					index++
+
+					if named, ok := tp.Constraint().(interface{ Obj() *types.TypeName }); ok {
+						if gType, ok := graphTypeMap[named.Obj()]; ok {
+							edges = append(edges, schema.Edge{
+								Source: gTypeParam,
+								Label:  "HasConstraint",
+								Target: gType,
+							})
+						}
+					}
+				}
+			}
+		}
+		for obj, gType := range pkgGraphTypes {
+			if named, ok := obj.Type().(*types.Named); ok && !obj.IsAlias() {
+				addTypeParams(gType, named.TypeParams())
+			}
+		}
+
+		// types from this package and its dependencies are all known now
+		for varType, gVar := range graphVarMap {
+			if edge, ok := hasTypeEdge(gVar, varType.Type(), graphTypeMap); ok {
+				edges = append(edges, edge)
+			}
+		}
+		for fieldType, gField := range pkgGraphFields {
+			if edge, ok := hasTypeEdge(gField, fieldType.Type(), graphTypeMap); ok {
+				edges = append(edges, edge)
+			}
+		}
+		for c, gConst := range pkgGraphConsts {
+			if edge, ok := hasTypeEdge(gConst, c.Type(), graphTypeMap); ok {
+				edges = append(edges, edge)
+			}
+		}
+
+		for pos, site := range createCallSites(pkg, graphVarMap, graphFieldMap, graphConstMap) {
+			callSites[pos] = site
+		}
+
+		// the CFG has range statements' channels as a statement of their own, which receives from them
+		rangedChans := map[ast.Node]bool{}
 		for _, root := range pkg.Syntax {
 			astutil.Apply(root, func(cur *astutil.Cursor) bool {
-				funcDecl, ok := cur.Node().(*ast.FuncDecl)
+				if rangeStmt, ok := cur.Node().(*ast.RangeStmt); ok {
+					if t := pkg.TypesInfo.TypeOf(rangeStmt.X); t != nil {
+						if _, ok := t.Underlying().(*types.Chan); ok {
+							rangedChans[rangeStmt.X] = true
+						}
+					}
+				}
+				return true
+			}, nil)
+		}
+
+		// Extract all function declarations and literals from the package
+		pkgGraphFuncs := map[*types.Func]*schema.Function{}
+		// the (narrowest) statement each function literal is in
+		litStmts := map[*ast.FuncLit]stmtWithLoc{}
+		// function literals get their own function (and statements), see createGraphFuncs
+		recordLits := func(node ast.Node, stmt stmtWithLoc) {
+			astutil.Apply(node, func(cur *astutil.Cursor) bool {
+				lit, ok := cur.Node().(*ast.FuncLit)
 				if !ok {
 					return true
 				}
-
-				if funcDecl.Body == nil {
-					return false
+				if prev, ok := litStmts[lit]; !ok || stmt.end-stmt.start < prev.end-prev.start {
+					litStmts[lit] = stmt
 				}
+				return false
+			}, nil)
+		}
 
-				logrus.Tracef("Processing function %q", funcDecl.Name.String())
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			if v, ok := scope.Lookup(name).(*types.Var); ok {
+				if gVar, ok := graphVarMap[v]; ok {
+					edges = append(edges, schema.Edge{
+						Source: graphPkg,
+						Label:  "Globals",
+						Target: gVar,
+					})
+				}
+			}
+		}
 
-				gFunc := &schema.Function{
-					Name:    funcDecl.Name.String(),
-					Package: graphPkg,
+		// Package level var initializers aren't in any function's CFG, so each gets a statement of its own.
+		// They're run (by the package initializer) in InitOrder, which follows dependencies rather than source order.
+		initStmts := map[ast.Expr]stmtWithLoc{}
+		for _, root := range pkg.Syntax {
+			for _, decl := range root.Decls {
+				genDecl, ok := decl.(*ast.GenDecl)
+				if !ok || genDecl.Tok != token.VAR {
+					continue
 				}
-				graphFuncMap[funcDecl] = gFunc
-				vertices <- gFunc
-				edges = append(edges, schema.Edge{
-					Source: gFunc.Package,
-					Label:  "Functions",
-					Target: gFunc,
-				})
+				for _, spec := range genDecl.Specs {
+					valueSpec, ok := spec.(*ast.ValueSpec)
+					if !ok || len(valueSpec.Values) == 0 {
+						continue
+					}
 
-				// And create a CFG for them
-				// TODO: Either copypasta or somehow call the ctrlflow pass to actually determine callMayReturn
-				// https://cs.opensource.google/go/x/tools/+/refs/tags/v0.1.8:go/analysis/passes/ctrlflow/ctrlflow.go;l=185;bpv=0;bpt=1
-				funcCFG := cfg.New(funcDecl.Body, func(_ *ast.CallExpr) bool { return true })
-
-				logrus.Trace("Created CFG")
-
-				// Create all statements, keeping track of the first and last in each BB
-				var funcFirstGStatement *schema.Statement
-				graphFirstStmtMap := map[*cfg.Block]*schema.Statement{}
-				graphLastStmtMap := map[*cfg.Block]*schema.Statement{}
-				for _, bb := range funcCFG.Blocks {
-					var prevGStmt *schema.Statement
-
-					for _, node := range bb.Nodes {
-						// statement isn't really an apt name, since at this point things like if conditional exprs
-						// have already been broken out
-						file, offset, text := fCache.readNodeSource(pkg.Fset, node /*limit=*/, 1024)
-						gStmt := &schema.Statement{
-							File:    file,
-							Offset:  offset,
-							Text:    text,
-							ASTType: reflect.TypeOf(node).Elem().Name(),
-						}
-						vertices <- gStmt
+					gStmt := newGraphStatement(valueSpec, pkg.Fset, fCache)
+					vertices <- gStmt
+					if gFile, ok := graphFileMap[gStmt.File]; ok {
 						edges = append(edges, schema.Edge{
-							Source: gFunc,
-							Label:  "Statement",
+							Source: gFile,
+							Label:  "Contains",
 							Target: gStmt,
 						})
-						pkgGraphStatements[pkg.PkgPath] = append(pkgGraphStatements[pkg.PkgPath], stmtWithLoc{node.Pos(), node.End(), gStmt})
+					}
+					stmt := stmtWithLoc{valueSpec.Pos(), valueSpec.End(), gStmt}
+					initGraphStatements[pkg.PkgPath] = append(initGraphStatements[pkg.PkgPath], stmt)
+					for _, value := range valueSpec.Values {
+						initStmts[value] = stmt
+					}
+
+					recordLits(valueSpec, stmt)
+					edges = append(edges, statementEdges(gStmt, valueSpec, pkg, graphVarMap, graphFieldMap, graphConstMap)...)
+					edges = append(edges, channelEdges(gStmt, valueSpec, false, pkg, graphVarMap, graphFieldMap)...)
+					edges = append(edges, typeInstanceEdges(gStmt, valueSpec, pkg, graphTypeMap, graphTypeParamMap, argTypes)...)
+				}
+			}
+		}
+		for i, initializer := range pkg.TypesInfo.InitOrder {
+			if stmt, ok := initStmts[initializer.Rhs]; ok {
+				edges = append(edges, schema.Edge{
+					Source: graphPkg,
+					Label:  "Initializers",
+					Target: stmt.gStmt,
+					Properties: map[string]interface{}{
+						"index": i,
+					},
+				})
+			}
+		}
+		funcDirectives := createGraphDirectives(pkg)
+
+		// init functions are run in the order they're presented to the compiler, after every initializer
+		inits := 0
+		for _, fn := range createGraphFuncs(pkg) {
+			gFunc := fn.gFunc
+			gFunc.Package = graphPkg
+			if funcDecl, ok := fn.node.(*ast.FuncDecl); ok {
+				if fnType, ok := pkg.TypesInfo.Defs[funcDecl.Name].(*types.Func); ok {
+					pkgGraphFuncs[fnType] = gFunc
+					sig := fnType.Type().(*types.Signature)
+					addTypeParams(gFunc, sig.RecvTypeParams(), sig.TypeParams())
+				}
+				for _, d := range funcDirectives[funcDecl] {
+					vertices <- d
+					edges = append(edges, schema.Edge{
+						Source: gFunc,
+						Label:  "Directives",
+						Target: d,
+					})
+				}
+				if funcDecl.Recv == nil && funcDecl.Name.Name == "init" {
+					edges = append(edges, schema.Edge{
+						Source: graphPkg,
+						Label:  "Inits",
+						Target: gFunc,
+						Properties: map[string]interface{}{
+							"index": inits,
+						},
+					})
+					inits++
+				}
+			}
 
-						if prevGStmt != nil {
+			logrus.Tracef("Processing function %q", gFunc.Symbol)
+
+			graphFuncMap[fn.node] = gFunc
+			vertices <- gFunc
+			edges = append(edges, schema.Edge{
+				Source: gFunc.Package,
+				Label:  "Functions",
+				Target: gFunc,
+			})
+			if gFile, ok := graphFileMap[pkg.Fset.File(fn.node.Pos()).Name()]; ok {
+				edges = append(edges, schema.Edge{
+					Source: gFile,
+					Label:  "Contains",
+					Target: gFunc,
+				})
+			}
+
+			if sig := funcSignature(fn.node, pkg); sig != nil {
+				for _, vars := range []struct {
+					label string
+					tuple *types.Tuple
+				}{{"Params", sig.Params()}, {"Results", sig.Results()}} {
+					for i := 0; i < vars.tuple.Len(); i++ {
+						if gVar, ok := graphVarMap[vars.tuple.At(i)]; ok {
 							edges = append(edges, schema.Edge{
-								Source: prevGStmt,
-								Label:  "Next",
-								Target: gStmt,
+								Source: gFunc,
+								Label:  vars.label,
+								Target: gVar,
 								Properties: map[string]interface{}{
-									"isBackEdge": false,
+									"index": i,
 								},
 							})
 						}
-						prevGStmt = gStmt
+					}
+				}
+			}
 
-						// is this the first statement in the entire function?
-						if funcFirstGStatement == nil {
-							funcFirstGStatement = gStmt
-							edges = append(edges, schema.Edge{
-								Source: gFunc,
-								Label:  "FirstStatement",
-								Target: gStmt,
-							})
-						}
+			if lit, ok := fn.node.(*ast.FuncLit); ok {
+				if stmt, ok := litStmts[lit]; ok {
+					edges = append(edges, schema.Edge{
+						Source: stmt.gStmt,
+						Label:  "Defines",
+						Target: gFunc,
+					})
+				}
+				for _, gVar := range capturedVars(lit, pkg, graphVarMap) {
+					edges = append(edges, schema.Edge{
+						Source: gFunc,
+						Label:  "Captures",
+						Target: gVar,
+					})
+				}
+			}
 
-						// Find all variables that anything under this stmt references (or defines)
-						refGVars := resolveIdents(node, pkg, graphVarMap)
-
-						// And all vars that this stmt may assign
-						var assignGVars []*schema.Variable
-						astutil.Apply(node, func(stmtCur *astutil.Cursor) bool {
-							switch node := stmtCur.Node().(type) {
-							case *ast.AssignStmt:
-								// a := 1 or a = 1
-								for _, child := range node.Lhs {
-									assignGVars = append(assignGVars, resolveIdents(child, pkg, graphVarMap)...)
-								}
-							case *ast.ValueSpec:
-								// var a int = 1
-								for _, child := range node.Names {
-									switch def := pkg.TypesInfo.Defs[child].(type) {
-									// TODO *types.Const
-									case *types.Var:
-										assignGVars = append(assignGVars, graphVarMap[def])
-									}
-								}
-							}
-							return true
-						}, nil)
-
-						for _, refGvar := range refGVars {
-							edges = append(edges, schema.Edge{
-								Source: gStmt,
-								Label:  "References",
-								Target: refGvar,
-							})
-						}
+			if fn.body == nil {
+				continue
+			}
 
-						for _, assignGvar := range assignGVars {
-							edges = append(edges, schema.Edge{
-								Source: gStmt,
-								Label:  "Assigns",
-								Target: assignGvar,
-							})
-						}
+			// And create a CFG for them
+			// TODO: Either copypasta or somehow call the ctrlflow pass to actually determine callMayReturn
+			// https://cs.opensource.google/go/x/tools/+/refs/tags/v0.1.8:go/analysis/passes/ctrlflow/ctrlflow.go;l=185;bpv=0;bpt=1
+			funcCFG := cfg.New(fn.body, func(_ *ast.CallExpr) bool { return true })
 
-						if graphFirstStmtMap[bb] == nil {
-							graphFirstStmtMap[bb] = gStmt
-						}
-						graphLastStmtMap[bb] = gStmt
+			logrus.Trace("Created CFG")
+
+			// Create all statements, keeping track of the first and last in each BB
+			var funcFirstGStatement *schema.Statement
+			graphFirstStmtMap := map[*cfg.Block]*schema.Statement{}
+			graphLastStmtMap := map[*cfg.Block]*schema.Statement{}
+			for _, bb := range funcCFG.Blocks {
+				var prevGStmt *schema.Statement
+
+				for _, node := range bb.Nodes {
+					// cfg makes falling off the end of the body an explicit return, which isn't in the source
+					if ret, ok := node.(*ast.ReturnStmt); ok && len(ret.Results) == 0 && ret.Return == fn.body.Rbrace {
+						continue
+					}
+
+					gStmt := newGraphStatement(node, pkg.Fset, fCache)
+					vertices <- gStmt
+					if gFile, ok := graphFileMap[gStmt.File]; ok {
+						edges = append(edges, schema.Edge{
+							Source: gFile,
+							Label:  "Contains",
+							Target: gStmt,
+						})
+					}
+					edges = append(edges, schema.Edge{
+						Source: gFunc,
+						Label:  "Statement",
+						Target: gStmt,
+					})
+					stmt := stmtWithLoc{node.Pos(), node.End(), gStmt}
+					funcGraphStatements[gFunc] = append(funcGraphStatements[gFunc], stmt)
+
+					recordLits(node, stmt)
+
+					if prevGStmt != nil {
+						edges = append(edges, schema.Edge{
+							Source: prevGStmt,
+							Label:  "Next",
+							Target: gStmt,
+							Properties: map[string]interface{}{
+								"isBackEdge": false,
+								"selectCase": "",
+							},
+						})
 					}
+					prevGStmt = gStmt
+
+					// is this the first statement in the entire function?
+					if funcFirstGStatement == nil {
+						funcFirstGStatement = gStmt
+						edges = append(edges, schema.Edge{
+							Source: gFunc,
+							Label:  "FirstStatement",
+							Target: gStmt,
+						})
+					}
+
+					edges = append(edges, statementEdges(gStmt, node, pkg, graphVarMap, graphFieldMap, graphConstMap)...)
+					edges = append(edges, channelEdges(gStmt, node, rangedChans[node], pkg, graphVarMap, graphFieldMap)...)
+					edges = append(edges, typeInstanceEdges(gStmt, node, pkg, graphTypeMap, graphTypeParamMap, argTypes)...)
+
+					if graphFirstStmtMap[bb] == nil {
+						graphFirstStmtMap[bb] = gStmt
+					}
+					graphLastStmtMap[bb] = gStmt
 				}
-				logrus.Trace("Created first/last statement maps")
+			}
+			logrus.Trace("Created first/last statement maps")
 
-				pruneEmpty(funcCFG, graphFirstStmtMap)
+			funcSelectCases := selectCases(funcCFG, graphFirstStmtMap, pkg.Fset, fCache)
+			pruneEmpty(funcCFG, graphFirstStmtMap)
 
-				cfgBackEdges := backEdges(funcCFG)
-				logrus.Trace("Created back-edge map")
+			cfgBackEdges := backEdges(funcCFG)
+			logrus.Trace("Created back-edge map")
 
-				// Link the edges between the last instruction in each BB and all possible successor BB's first statements
-				for _, bb := range funcCFG.Blocks {
-					for _, succ := range bb.Succs {
-						isBackEdge := cfgBackEdges[bb].Contains(succ)
+			// Link the edges between the last instruction in each BB and all possible successor BB's first statements
+			for _, bb := range funcCFG.Blocks {
+				for _, succ := range bb.Succs {
+					isBackEdge := cfgBackEdges[bb].Contains(succ)
 
+					// one edge per select case which leads to succ
+					cases := []string{""}
+					if bbCases, ok := funcSelectCases[bb]; ok {
+						cases = bbCases[succ]
+					}
+					for _, selectCase := range cases {
 						edges = append(edges, schema.Edge{
 							Source: graphLastStmtMap[bb],
 							Label:  "Next",
 							Target: graphFirstStmtMap[succ],
 							Properties: map[string]interface{}{
 								"isBackEdge": isBackEdge,
+								"selectCase": selectCase,
 							},
 						})
 					}
 				}
+			}
 
-				return false
-			}, nil)
+		}
+
+		for obj, gType := range pkgGraphTypes {
+			named, ok := obj.Type().(*types.Named)
+			if !ok || obj.IsAlias() {
+				continue
+			}
+
+			for i := 0; i < named.NumMethods(); i++ {
+				if gFunc, ok := pkgGraphFuncs[named.Method(i)]; ok {
+					edges = append(edges, schema.Edge{
+						Source: gType,
+						Label:  "Methods",
+						Target: gFunc,
+					})
+				}
+			}
+		}
+
+		// type arguments can be type parameters of this package's functions, which are all known now
+		for varType, gVar := range graphVarMap {
+			edges = append(edges, declaredInstanceEdges(gVar, varType.Type(), graphTypeMap, graphTypeParamMap, argTypes)...)
+		}
+		for fieldType, gField := range pkgGraphFields {
+			edges = append(edges, declaredInstanceEdges(gField, fieldType.Type(), graphTypeMap, graphTypeParamMap, argTypes)...)
 		}
 	})
 
+	// every loaded package's types are known now, so interface satisfaction can be checked across all of them
+	edges = append(edges, implementsEdges(graphTypeMap, pkgIsNew)...)
+
 	ssaProg.Build()
 
 	// use RTA instead? would require the "global graph" to be almost flow-sensitive, but would remove unreachable
@@ -537,47 +2120,125 @@ func processPackage(pkgDir string, backend gbackend.Backend) {
 	logrus.Trace("Created callgraph")
 
 	// create the calls edges (and FunctionCall intermediate vertices)
+	type callKey struct {
+		caller, callee ast.Node
+		site           token.Pos
+	}
+	seenCalls := map[callKey]bool{}
 	callgraph.GraphVisitEdges(cg, func(edge *callgraph.Edge) error {
-		callerNode, ok := edge.Caller.Func.Syntax().(*ast.FuncDecl)
-		if !ok {
+		// *ast.FuncDecl or *ast.FuncLit, nil for synthetic functions (wrappers, package initializers, ...)
+		callerNode := edge.Caller.Func.Syntax()
+		// calls from package level var initializers are made by the package initializer, which has no function vertex
+		isInitializer := edge.Caller.Func.Pkg != nil && edge.Caller.Func == edge.Caller.Func.Pkg.Func("init")
+		if callerNode == nil && !isInitializer {
 			return nil
 		}
 
-		// only insert if the call is in a new (not in DB) package
-		pkgName := edge.Site.Parent().Pkg.Pkg.Path()
+		// only insert if the call is in a new (not in DB) package.
+		// Instantiations of generic functions (and functions nested in them) have no package, their generic function does
+		siteFunc := edge.Site.Parent()
+		if origin := siteFunc.Origin(); origin != nil {
+			siteFunc = origin
+		}
+		if siteFunc.Pkg == nil {
+			return nil
+		}
+		pkgName := siteFunc.Pkg.Pkg.Path()
 		if !pkgIsNew[pkgName] {
 			return nil
 		}
 
-		calleeNode, ok := edge.Callee.Func.Syntax().(*ast.FuncDecl)
-		if !ok {
+		calleeNode := edge.Callee.Func.Syntax()
+		if calleeNode == nil {
 			return nil
 		}
 
-		if caller, found := graphFuncMap[callerNode]; found {
+		caller, found := graphFuncMap[callerNode]
+		statements := funcGraphStatements[caller]
+		if isInitializer {
+			// the package initializer also calls init functions and dependencies' initializers, which don't have a
+			// statement
+			found = true
+			statements = initGraphStatements[pkgName]
+		}
+
+		// every instantiation of a generic function has the same calls
+		key := callKey{callerNode, calleeNode, edge.Site.Pos()}
+		if found && !seenCalls[key] {
+			seenCalls[key] = true
 			if callee, found := graphFuncMap[calleeNode]; found {
+				// statements can contain others (e.g. a range statement, its body), so pick the narrowest one
+				var callStmt *stmtWithLoc
+				for i, stmt := range statements {
+					if stmt.start <= edge.Site.Pos() && stmt.end > edge.Site.Pos() {
+						if callStmt == nil || stmt.end-stmt.start < callStmt.end-callStmt.start {
+							callStmt = &statements[i]
+						}
+					}
+				}
+				if isInitializer && callStmt == nil {
+					return nil
+				}
+
 				fc := &schema.FunctionCall{
+					Kind:   "call",
 					Caller: caller,
 					Callee: callee,
 				}
+				switch edge.Site.(type) {
+				case *ssa.Go:
+					fc.Kind = "go"
+				case *ssa.Defer:
+					fc.Kind = "defer"
+				}
 				vertices <- fc
-				edges = append(
-					edges,
-					schema.Edge{Source: fc.Caller, Label: "Calls", Target: fc},
-					schema.Edge{Source: fc, Label: "Callee", Target: fc.Callee},
-				)
-
-				pkgName := edge.Site.Parent().Pkg.Pkg.Path()
-				for _, stmt := range pkgGraphStatements[pkgName] {
-					if stmt.start <= edge.Site.Pos() && stmt.end > edge.Site.Pos() {
-						edges = append(
-							edges,
-							schema.Edge{Source: fc, Label: "CallSiteStatement", Target: stmt.gStmt},
-						)
+				if fc.Caller != nil {
+					edges = append(edges, schema.Edge{Source: fc.Caller, Label: "Calls", Target: fc})
+					switch fc.Kind {
+					case "go":
+						edges = append(edges, schema.Edge{Source: fc.Caller, Label: "Spawns", Target: fc})
+					case "defer":
+						edges = append(edges, schema.Edge{Source: fc.Caller, Label: "Defers", Target: fc})
+					}
+				}
+				edges = append(edges, schema.Edge{Source: fc, Label: "Callee", Target: fc.Callee})
 
-						break
+				if site, ok := callSites[edge.Site.Pos()]; ok {
+					for i, arg := range site.args {
+						for _, v := range arg {
+							edges = append(edges, schema.Edge{
+								Source: fc,
+								Label:  "Arg",
+								Target: v,
+								Properties: map[string]interface{}{
+									"index": i,
+								},
+							})
+						}
+					}
+					for i, v := range site.results {
+						if v != nil {
+							edges = append(edges, schema.Edge{
+								Source: fc,
+								Label:  "ReturnsTo",
+								Target: v,
+								Properties: map[string]interface{}{
+									"index": i,
+								},
+							})
+						}
+					}
+					if site.typeArgs != nil {
+						edges = append(edges, instantiatesEdges(fc, site.generic, site.typeArgs, graphTypeMap, graphTypeParamMap, argTypes)...)
 					}
 				}
+
+				if callStmt != nil {
+					edges = append(
+						edges,
+						schema.Edge{Source: fc, Label: "CallSiteStatement", Target: callStmt.gStmt},
+					)
+				}
 			}
 		}
 
@@ -585,19 +2246,26 @@ func processPackage(pkgDir string, backend gbackend.Backend) {
 	})
 	logrus.Trace("Callgraph nodes created")
 
+	edges = append(edges, argTypes.edges...)
+
 	close(vertices)
-	vertexWG.Wait()
+	if err := vertexWorkers.Wait(); err != nil {
+		logrus.Fatalf("Error adding vertices for %q: %v", pkgDir, err)
+	}
 
 	logrus.Infof("Added %d vertices", verticesCount)
 
 	if noProgressBar {
-		backend.AddEBulk(edges, func(doneEdges []schema.Edge) {})
+		err = backend.AddEBulk(edges, func(doneEdges []schema.Edge) {})
 	} else {
 		progress := progressbar.Default(int64(len(edges)))
-		backend.AddEBulk(edges, func(doneEdges []schema.Edge) {
+		err = backend.AddEBulk(edges, func(doneEdges []schema.Edge) {
 			progress.Add(len(doneEdges))
 		})
 	}
+	if err != nil {
+		logrus.Fatalf("Error adding edges for %q: %v", pkgDir, err)
+	}
 }
 
 var noProgressBar bool
@@ -609,7 +2277,7 @@ func main() {
 
 	profile := flag.Bool("profile", false, "Save cpu and mem profiles")
 
-	conn := flag.String("db", "ws://localhost:8182", "DB connection string")
+	conn := flag.String("db", "ws://localhost:8182", "DB URL, the scheme picks the backend (see README)")
 
 	flag.Parse()
 
@@ -628,25 +2296,23 @@ func main() {
 	if *profile {
 		cpu, err := os.Create("cpuprofile")
 		if err != nil {
-			logrus.Fatal("Error creating cpuprofile: %v", err)
+			logrus.Fatalf("Error creating cpuprofile: %v", err)
 		}
 		defer cpu.Close()
 		if err := pprof.StartCPUProfile(cpu); err != nil {
-			logrus.Fatal("Error starting profiling: %v", err)
+			logrus.Fatalf("Error starting profiling: %v", err)
 		}
 		defer pprof.StopCPUProfile()
 
 		mem, err := os.Create("memprofile")
 		if err != nil {
-			logrus.Fatal("Error creating memprofile: %v", err)
+			logrus.Fatalf("Error creating memprofile: %v", err)
 		}
 		defer mem.Close()
 		defer pprof.WriteHeapProfile(mem)
 	}
 
-	var backend gbackend.Backend
-	var err error
-	backend, err = gbackend.NewArangoBackend(*conn, "go-graph")
+	backend, err := gbackend.NewBackend(*conn)
 	if err != nil {
 		logrus.Fatalf("Error creating backend: %v", err)
 	}
@@ -0,0 +1,889 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	gbackend "github.com/kallsyms/go-graph/backend"
+	"github.com/kallsyms/go-graph/coordination"
+	"github.com/kallsyms/go-graph/schema"
+	"github.com/sirupsen/logrus"
+	logtest "github.com/sirupsen/logrus/hooks/test"
+)
+
+func TestNoImplicitReturns(t *testing.T) {
+	noProgressBar = true
+
+	backend := gbackend.NewMemoryBackend()
+	processPackage("testdata/implicitreturn", backend)
+
+	stmts := backend.V("statement").ToList()
+	if len(stmts) == 0 {
+		t.Fatal("No statements ingested")
+	}
+	for _, v := range stmts {
+		stmt := v.(*schema.Statement)
+		if strings.HasPrefix(stmt.Text, "}") {
+			t.Errorf("Statement %q at %s:%d:%d is cfg's implicit return", stmt.Text, stmt.File, stmt.StartLine, stmt.StartColumn)
+		}
+	}
+}
+
+func TestFieldArg(t *testing.T) {
+	noProgressBar = true
+
+	backend := gbackend.NewMemoryBackend()
+	processPackage("testdata/fieldarg", backend)
+
+	// wait(s.conf.Timeout)
+	calls := backend.V("function").Has("Name", "wait").In("Callee").ToList()
+	if len(calls) != 1 {
+		t.Fatalf("Found %d calls to wait, want 1", len(calls))
+	}
+
+	args := map[string]bool{}
+	edges, err := backend.OutEdges(calls[0], "Arg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range edges {
+		if f, ok := e.Target.(*schema.Field); ok {
+			args[f.Name] = true
+		}
+	}
+	for _, name := range []string{"conf", "Timeout"} {
+		if !args[name] {
+			t.Errorf("No Arg edge to field %s", name)
+		}
+	}
+}
+
+func TestTypeArgs(t *testing.T) {
+	noProgressBar = true
+
+	backend := gbackend.NewMemoryBackend()
+	processPackage("testdata/typeargs", backend)
+
+	typeArgs := map[string]string{}
+	for _, v := range backend.V().ToList() {
+		edges, err := backend.OutEdges(v, "Instantiates")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range edges {
+			typeArg, _ := e.Properties["typeArg"].(string)
+			switch target := e.Target.(type) {
+			case *schema.Type:
+				typeArgs[typeArg] = target.Symbol
+			case *schema.TypeParam:
+				typeArgs[typeArg] = target.Name
+			}
+		}
+	}
+
+	for typeArg, symbol := range map[string]string{
+		"int":                         "int",
+		"string":                      "string",
+		"[]byte":                      "[]byte",
+		"*struct{}":                   "*struct{}",
+		"func()":                      "func()",
+		"chan example.com/typeargs.T": "chan example.com/typeargs.T",
+		"*example.com/typeargs.T":     "example.com/typeargs.T",
+		// declared in Local, so not the package's T
+		"example.com/typeargs.T":   "example.com/typeargs.T·typeargs.go:28",
+		"[]example.com/typeargs.T": "[]example.com/typeargs.T·typeargs.go:28",
+	} {
+		if got, ok := typeArgs[typeArg]; !ok {
+			t.Errorf("No Instantiates edge for type argument %s", typeArg)
+		} else if got != symbol {
+			t.Errorf("Instantiates edge for type argument %s goes to %s, want %s", typeArg, got, symbol)
+		}
+	}
+}
+
+// Type arguments with no declaration of their own are shared by every ingest
+func TestTypeArgsReused(t *testing.T) {
+	noProgressBar = true
+
+	backend := gbackend.NewMemoryBackend()
+	processPackage("testdata/typeargs", backend)
+	types := backend.V("type").Count()
+	processPackage("testdata/typeargs", backend)
+	if n := backend.V("type").Count(); n != types {
+		t.Errorf("%d types after ingesting again, want %d", n, types)
+	}
+	processPackage("testdata/typeargs/again", backend)
+
+	ints := backend.V("type").Has("Symbol", "int").ToList()
+	if len(ints) != 1 {
+		t.Fatalf("%d int types, want 1", len(ints))
+	}
+	if pkgs := backend.From(ints[0]).In("Types").ToList(); len(pkgs) != 1 || vertexName(pkgs[0]) != "builtin" {
+		t.Errorf("int is in packages %v, want only builtin", pkgs)
+	}
+	users := []string{}
+	for _, v := range backend.From(ints[0]).In("Instantiates").ToList() {
+		if _, ok := v.(*schema.FunctionCall); ok {
+			for _, callee := range backend.From(v).Out("Callee").ToList() {
+				users = append(users, "call to "+vertexName(callee))
+			}
+		} else {
+			users = append(users, vertexName(v))
+		}
+	}
+	sort.Strings(users)
+	// List[int] is used by the variable ints (and the statement declaring it), and Zero[int] by N's initializer
+	if want := []string{"call to example.com/again.Zero", "ints", "ints List[int]"}; !reflect.DeepEqual(users, want) {
+		t.Errorf("int is instantiated by %q, want %q", users, want)
+	}
+}
+
+func TestFunctionSymbols(t *testing.T) {
+	noProgressBar = true
+	hook := logtest.NewGlobal()
+	defer hook.Reset()
+
+	backend := gbackend.NewMemoryBackend()
+	processPackage("testdata/symbols", backend)
+
+	symbols := []string{}
+	for _, v := range backend.V("function").ToList() {
+		symbols = append(symbols, v.(*schema.Function).Symbol)
+	}
+	sort.Strings(symbols)
+	want := []string{
+		"example.com/symbols.(*List).Len",
+		"example.com/symbols.(*U).Close",
+		"example.com/symbols.(*U).String",
+		"example.com/symbols.(*U)._.0",
+		"example.com/symbols.T.Close",
+		"example.com/symbols.T.String",
+		"example.com/symbols.T._.0",
+		"example.com/symbols.T._.1",
+		"example.com/symbols._.0",
+		"example.com/symbols.init.0",
+		"example.com/symbols.init.1",
+	}
+	if !reflect.DeepEqual(symbols, want) {
+		t.Errorf("Function symbols = %q, want %q", symbols, want)
+	}
+
+	// seeing the package again relinks every function by symbol, without adding any
+	processPackage("testdata/symbols", backend)
+	if n := len(backend.V("function").ToList()); n != len(want) {
+		t.Errorf("%d functions after ingesting again, want %d", n, len(want))
+	}
+	for _, entry := range hook.AllEntries() {
+		if entry.Level <= logrus.WarnLevel {
+			t.Errorf("Unexpected %s: %s", entry.Level, entry.Message)
+		}
+	}
+}
+
+func TestSQLiteQueries(t *testing.T) {
+	noProgressBar = true
+
+	backend, err := gbackend.NewSQLiteBackend(filepath.Join(t.TempDir(), "graph.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	processPackage("testdata/queries", backend)
+
+	sites, err := backend.CallSites("example.com/queries", "example.com/queries.(*T).Close")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := statementTexts(sites); !reflect.DeepEqual(got, []string{"t.Close()"}) {
+		t.Errorf("CallSites((*T).Close) = %v, want only t.Close()", got)
+	}
+	sites, err = backend.CallSites("example.com/queries", "Close")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := statementTexts(sites)
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, []string{"t.Close()", "u.Close()"}) {
+		t.Errorf("CallSites(Close) = %v, want both calls", got)
+	}
+
+	for depth, want := range [][]string{{}, {"b"}, {"b", "c"}, {"b", "c", "leaf"}, {"b", "c", "leaf"}} {
+		callees, err := backend.TransitiveCallees("example.com/queries", "A", depth)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := []string{}
+		for _, f := range callees {
+			got = append(got, f.Name)
+		}
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("TransitiveCallees(A, %d) = %v, want %v", depth, got, want)
+		}
+	}
+
+	paths, err := backend.StatementPaths("example.com/queries", "Sum", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = []string{}
+	for _, path := range paths {
+		got = append(got, strings.Join(statementTexts(path), "; "))
+	}
+	sort.Strings(got)
+	// the loop's back edge (i++ -> i < n) isn't followed
+	want := []string{
+		"x := 0; i := 0; i < n; return x",
+		"x := 0; i := 0; i < n; x += i; i++",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("StatementPaths(Sum) = %q, want %q", got, want)
+	}
+
+	paths, err = backend.StatementPaths("example.com/queries", "Sum", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 1 || len(paths[0]) != 3 {
+		t.Errorf("StatementPaths(Sum, 2) = %d paths, want 1 cut off after 2 steps", len(paths))
+	}
+}
+
+// statementTexts returns the Text of each statement
+func statementTexts(stmts []*schema.Statement) []string {
+	texts := []string{}
+	for _, s := range stmts {
+		texts = append(texts, s.Text)
+	}
+	return texts
+}
+
+// Backends with fixed columns (like neo4j-csv) refuse edge properties which aren't in schema.EdgeDefinitions
+func TestEdgePropertiesDeclared(t *testing.T) {
+	noProgressBar = true
+
+	declared := map[string]map[string]interface{}{}
+	for _, def := range schema.EdgeDefinitions {
+		declared[def.Label] = def.Properties
+	}
+
+	dirs, err := filepath.Glob("testdata/*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range dirs {
+		backend := gbackend.NewMemoryBackend()
+		processPackage(dir, backend)
+
+		err := backend.ScanEdges(func(e schema.Edge) error {
+			for k := range e.Properties {
+				if _, ok := declared[e.Label][k]; !ok {
+					t.Errorf("%s: %s edge has undeclared property %q", dir, e.Label, k)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// ingest processes the package in testdata/dir into a new memory backend
+func ingest(dir string) *gbackend.MemoryBackend {
+	noProgressBar = true
+
+	backend := gbackend.NewMemoryBackend()
+	processPackage(filepath.Join("testdata", dir), backend)
+	return backend
+}
+
+// vertexName names v in expectations: its Symbol, Name, Text, Path or SourceURL, whichever is set first
+func vertexName(v schema.Vertex) string {
+	props := v.Properties()
+	for _, k := range []string{"Symbol", "Name", "Text", "Path", "SourceURL"} {
+		if s, _ := props[k].(string); s != "" {
+			return s
+		}
+	}
+	return v.Label()
+}
+
+// edgesLabelled returns every label edge in backend as "source -> target" (see vertexName), with its properties
+func edgesLabelled(t *testing.T, backend *gbackend.MemoryBackend, label string) map[string]map[string]interface{} {
+	edges := map[string]map[string]interface{}{}
+	err := backend.ScanEdges(func(e schema.Edge) error {
+		if e.Label == label {
+			edges[vertexName(e.Source)+" -> "+vertexName(e.Target)] = e.Properties
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return edges
+}
+
+// expectEdges checks that backend has every edge in want (keyed like edgesLabelled), with at least the given
+// properties
+func expectEdges(t *testing.T, backend *gbackend.MemoryBackend, label string, want map[string]map[string]interface{}) {
+	t.Helper()
+	edges := edgesLabelled(t, backend, label)
+	for edge, props := range want {
+		got, ok := edges[edge]
+		if !ok {
+			t.Errorf("No %s edge %s", label, edge)
+			continue
+		}
+		for k, v := range props {
+			if got[k] != v {
+				t.Errorf("%s edge %s has %s = %v, want %v", label, edge, k, got[k], v)
+			}
+		}
+	}
+}
+
+// unexpectEdges checks that backend has none of the label edges in edges
+func unexpectEdges(t *testing.T, backend *gbackend.MemoryBackend, label string, edges ...string) {
+	t.Helper()
+	got := edgesLabelled(t, backend, label)
+	for _, edge := range edges {
+		if _, ok := got[edge]; ok {
+			t.Errorf("Unexpected %s edge %s", label, edge)
+		}
+	}
+}
+
+func TestTypes(t *testing.T) {
+	backend := ingest("types")
+
+	expectEdges(t, backend, "Types", map[string]map[string]interface{}{
+		"example.com/types -> example.com/types.Base":   nil,
+		"example.com/types -> example.com/types.Config": nil,
+		"example.com/types -> example.com/types.Server": nil,
+		"example.com/types -> example.com/types.Port":   nil,
+	})
+	expectEdges(t, backend, "Fields", map[string]map[string]interface{}{
+		"example.com/types.Server -> Base":    nil,
+		"example.com/types.Server -> Name":    nil,
+		"example.com/types.Server -> Peers":   nil,
+		"example.com/types.Server -> conf":    nil,
+		"example.com/types.Config -> Timeout": nil,
+	})
+	expectEdges(t, backend, "Methods", map[string]map[string]interface{}{
+		"example.com/types.Server -> example.com/types.(*Server).Start": nil,
+		"example.com/types.Server -> example.com/types.Server.Addr":     nil,
+	})
+	expectEdges(t, backend, "HasType", map[string]map[string]interface{}{
+		"Base -> example.com/types.Base":        {"isPointer": false},
+		"conf -> example.com/types.Config":      {"isPointer": true},
+		"DefaultPort -> example.com/types.Port": {"isPointer": false},
+	})
+	// only named types (through pointers) get one
+	unexpectEdges(t, backend, "HasType", "Peers -> example.com/types.Server")
+
+	fields := map[string]*schema.Field{}
+	for _, v := range backend.V("type").Has("Symbol", "example.com/types.Server").Out("Fields").ToList() {
+		f := v.(*schema.Field)
+		fields[f.Name] = f
+	}
+	if f := fields["Base"]; f == nil || !f.Embedded || f.Index != 0 {
+		t.Errorf("Base field = %+v, want embedded at index 0", f)
+	}
+	if f := fields["Name"]; f == nil || f.Tag != `json:"name"` || f.Type != "string" || f.Index != 1 {
+		t.Errorf("Name field = %+v, want string at index 1 with its tag", f)
+	}
+
+	kinds := map[string]string{}
+	for _, v := range backend.V("type").ToList() {
+		kinds[v.(*schema.Type).Name] = v.(*schema.Type).Kind
+	}
+	if kinds["Server"] != "struct" || kinds["Port"] != "basic" {
+		t.Errorf("Type kinds = %v, want Server struct and Port basic", kinds)
+	}
+}
+
+func TestImplements(t *testing.T) {
+	backend := ingest("implements")
+
+	expectEdges(t, backend, "Implements", map[string]map[string]interface{}{
+		"example.com/implements.File -> example.com/implements.Closer":     {"isPointer": false},
+		"example.com/implements.Conn -> example.com/implements.Closer":     {"isPointer": true},
+		"example.com/implements.Conn -> example.com/implements.ReadCloser": {"isPointer": true},
+		// interfaces from dependencies count too
+		"example.com/implements.File -> fmt.Stringer": {"isPointer": false},
+	})
+	unexpectEdges(t, backend, "Implements",
+		"example.com/implements.File -> example.com/implements.ReadCloser",
+		// empty interfaces would match everything
+		"example.com/implements.File -> example.com/implements.Any",
+		// nor do interfaces implement each other
+		"example.com/implements.ReadCloser -> example.com/implements.Closer",
+	)
+}
+
+// packageTuples returns "path@version" for each package, sorted
+func packageTuples(pkgs []*schema.Package) []string {
+	tuples := []string{}
+	for _, p := range pkgs {
+		tuples = append(tuples, p.SourceURL+"@"+p.Version)
+	}
+	sort.Strings(tuples)
+	return tuples
+}
+
+func TestImports(t *testing.T) {
+	noProgressBar = true
+
+	// another version of the dependency is already stored, which mustn't be linked to
+	backend := gbackend.NewMemoryBackend()
+	if _, err := backend.CreatePackage(coordination.PackageTuple{Name: "example.com/dep", Version: "v1.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+	processPackage("testdata/imports", backend)
+
+	imports := func(path string) []string {
+		pkgs := []*schema.Package{}
+		for _, v := range backend.V("package").Has("SourceURL", path).Out("Imports").ToList() {
+			pkgs = append(pkgs, v.(*schema.Package))
+		}
+		return packageTuples(pkgs)
+	}
+	for path, want := range map[string][]string{
+		"example.com/imports":     {"example.com/dep@v1.2.3", "example.com/imports/sub@"},
+		"example.com/imports/sub": {"example.com/dep@v1.2.3"},
+		"example.com/dep":         {"example.com/dep/inner@v1.2.3"},
+		"example.com/dep/inner":   {},
+	} {
+		if got := imports(path); !reflect.DeepEqual(got, want) {
+			t.Errorf("%s imports %v, want %v", path, got, want)
+		}
+	}
+}
+
+func TestSQLiteImports(t *testing.T) {
+	noProgressBar = true
+
+	backend, err := gbackend.NewSQLiteBackend(filepath.Join(t.TempDir(), "graph.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	processPackage("testdata/imports", backend)
+
+	for _, tt := range []struct {
+		path, version string
+		want          []string
+	}{
+		{"example.com/imports", "", []string{"example.com/dep/inner@v1.2.3", "example.com/dep@v1.2.3", "example.com/imports/sub@"}},
+		{"example.com/imports/sub", "", []string{"example.com/dep/inner@v1.2.3", "example.com/dep@v1.2.3"}},
+		{"example.com/dep/inner", "v1.2.3", []string{}},
+		{"example.com/dep", "v1.0.0", []string{}},
+	} {
+		pkgs, err := backend.TransitiveImports(tt.path, tt.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := packageTuples(pkgs); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("TransitiveImports(%s@%s) = %v, want %v", tt.path, tt.version, got, tt.want)
+		}
+	}
+
+	for _, tt := range []struct {
+		path, version string
+		want          []string
+	}{
+		{"example.com/dep", "v1.2.3", []string{"example.com/imports/sub@", "example.com/imports@"}},
+		{"example.com/dep/inner", "v1.2.3", []string{"example.com/dep@v1.2.3"}},
+		{"example.com/imports", "", []string{}},
+		{"example.com/dep", "v1.0.0", []string{}},
+	} {
+		pkgs, err := backend.Importers(tt.path, tt.version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := packageTuples(pkgs); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Importers(%s@%s) = %v, want %v", tt.path, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestFiles(t *testing.T) {
+	backend := ingest("files")
+
+	expectEdges(t, backend, "Files", map[string]map[string]interface{}{
+		"example.com/files -> add.go":   nil,
+		"example.com/files -> twice.go": nil,
+	})
+	expectEdges(t, backend, "Contains", map[string]map[string]interface{}{
+		"add.go -> example.com/files.Add":     nil,
+		"add.go -> sum := a + b":              nil,
+		"add.go -> return sum":                nil,
+		"twice.go -> example.com/files.Twice": nil,
+		"twice.go -> return Add(n, n)":        nil,
+	})
+	unexpectEdges(t, backend, "Contains", "add.go -> example.com/files.Twice", "twice.go -> return sum")
+
+	for _, v := range backend.V("file").ToList() {
+		f := v.(*schema.File)
+		contents, err := os.ReadFile(filepath.Join("testdata/files", f.Path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := fmt.Sprintf("%x", sha256.Sum256(contents)); f.Hash != want || f.Package != "example.com/files" || f.Module != "example.com/files" {
+			t.Errorf("File %+v, want hash %s in example.com/files", f, want)
+		}
+	}
+
+	// columns are 1-based bytes (so a tab is one), and the end is just after the statement
+	stmts := map[string]*schema.Statement{}
+	for _, v := range backend.V("statement").ToList() {
+		stmts[v.(*schema.Statement).Text] = v.(*schema.Statement)
+	}
+	for text, want := range map[string][4]int{
+		"sum := a + b":     {4, 2, 4, 14},
+		"return sum":       {5, 2, 5, 12},
+		"return Add(n, n)": {4, 25, 4, 41},
+	} {
+		s, ok := stmts[text]
+		if !ok {
+			t.Errorf("No statement %q", text)
+			continue
+		}
+		if got := [4]int{s.StartLine, s.StartColumn, s.EndLine, s.EndColumn}; got != want {
+			t.Errorf("Statement %q spans %v, want %v", text, got, want)
+		}
+	}
+}
+
+func TestClosures(t *testing.T) {
+	backend := ingest("closures")
+
+	expectEdges(t, backend, "Defines", map[string]map[string]interface{}{
+		"inc := func() int {\n\t\tn++\n\t\treturn n\n\t} -> example.com/closures.Counter.func1":                         nil,
+		"f := func() int {\n\t\tg := func() int { return x }\n\t\treturn g()\n\t} -> example.com/closures.Nested.func1": nil,
+		"g := func() int { return x } -> example.com/closures.Nested.func1.1":                                           nil,
+		"return func(y int) int { return y * 2 }(1) -> example.com/closures.Local.func1":                                nil,
+		"Global = func(s string) string { return s } -> example.com/closures.glob..func1":                               nil,
+	})
+	expectEdges(t, backend, "Captures", map[string]map[string]interface{}{
+		"example.com/closures.Counter.func1 -> n": nil,
+		// x is only used by the inner literal, but the outer one has to capture it for it
+		"example.com/closures.Nested.func1 -> x":   nil,
+		"example.com/closures.Nested.func1.1 -> x": nil,
+	})
+	// parameters and locals of the literal itself aren't captured
+	unexpectEdges(t, backend, "Captures",
+		"example.com/closures.Nested.func1 -> g",
+		"example.com/closures.Local.func1 -> y",
+		"example.com/closures.glob..func1 -> s",
+	)
+
+	// the literal's statements are its own, and calling it goes to it
+	expectEdges(t, backend, "Statement", map[string]map[string]interface{}{
+		"example.com/closures.Counter.func1 -> n++": nil,
+	})
+	unexpectEdges(t, backend, "Statement", "example.com/closures.Counter -> n++")
+	callees := map[string]bool{}
+	for _, v := range backend.V("function").Has("Symbol", "example.com/closures.Nested.func1").Out("Calls").Out("Callee").ToList() {
+		callees[vertexName(v)] = true
+	}
+	if !callees["example.com/closures.Nested.func1.1"] {
+		t.Errorf("Nested.func1 calls %v, want Nested.func1.1", callees)
+	}
+}
+
+func TestConstants(t *testing.T) {
+	backend := ingest("constants")
+
+	expectEdges(t, backend, "Constants", map[string]map[string]interface{}{
+		"example.com/constants -> example.com/constants.Max":      nil,
+		"example.com/constants -> example.com/constants.A":        nil,
+		"example.com/constants -> example.com/constants.B":        nil,
+		"example.com/constants -> example.com/constants.Fast":     nil,
+		"example.com/constants -> example.com/constants.Greeting": nil,
+		"math -> math.MaxInt8":                                    nil,
+	})
+	expectEdges(t, backend, "References", map[string]map[string]interface{}{
+		"n > Max -> example.com/constants.Max":                                            nil,
+		"return n != local && n < limit && Mode(n) != Fast -> example.com/constants.Fast": nil,
+		// local constants have no symbol, and constants from dependencies are the dependency's vertex
+		"return n != local && n < limit && Mode(n) != Fast -> local": nil,
+		"limit := math.MaxInt8 -> math.MaxInt8":                      nil,
+	})
+	expectEdges(t, backend, "HasType", map[string]map[string]interface{}{
+		"example.com/constants.Fast -> example.com/constants.Mode": {"isPointer": false},
+	})
+
+	values := map[string]*schema.Constant{}
+	for _, v := range backend.V("constant").ToList() {
+		c := v.(*schema.Constant)
+		values[c.Name] = c
+	}
+	for name, want := range map[string][2]string{
+		"Max":      {"10", "untyped int"},
+		"B":        {"1", "untyped int"},
+		"Fast":     {"1", "example.com/constants.Mode"},
+		"Greeting": {`"hi"`, "untyped string"},
+		"local":    {"3", "untyped int"},
+	} {
+		c, ok := values[name]
+		if !ok {
+			t.Errorf("No constant %s", name)
+		} else if got := [2]string{c.Value, c.Type}; got != want {
+			t.Errorf("Constant %s has value and type %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestFieldAccess(t *testing.T) {
+	backend := ingest("fields")
+
+	expectEdges(t, backend, "ReadsField", map[string]map[string]interface{}{
+		"o.Total += o.Count -> Total": {"isPromoted": false},
+		"o.Total += o.Count -> Count": {"isPromoted": true},
+		"o.Count++ -> Count":          {"isPromoted": true},
+		"o.Inner.Count = 2 -> Inner":  {"isPromoted": false},
+		"return o.Label, c -> Label":  {"isPromoted": false},
+	})
+	expectEdges(t, backend, "WritesField", map[string]map[string]interface{}{
+		`o.Label = "updated" -> Label`:  {"isPromoted": false},
+		"o.Total += o.Count -> Total":   {"isPromoted": false},
+		"o.Count++ -> Count":            {"isPromoted": true},
+		"o.Inner.Count = 2 -> Count":    {"isPromoted": false},
+		"c := Inner{Count: 3} -> Count": {"isPromoted": false},
+	})
+	// plain assignments don't read what they write
+	unexpectEdges(t, backend, "ReadsField",
+		`o.Label = "updated" -> Label`,
+		"o.Inner.Count = 2 -> Count",
+		"c := Inner{Count: 3} -> Count",
+	)
+	unexpectEdges(t, backend, "WritesField", "o.Total += o.Count -> Count", "o.Inner.Count = 2 -> Inner")
+}
+
+func TestSignatures(t *testing.T) {
+	backend := ingest("signatures")
+
+	expectEdges(t, backend, "Params", map[string]map[string]interface{}{
+		"example.com/signatures.Divide -> num":      {"index": 0},
+		"example.com/signatures.Divide -> den":      {"index": 1},
+		"example.com/signatures.(*Calc).Apply -> f": {"index": 0},
+		"example.com/signatures.(*Calc).Apply -> x": {"index": 1},
+		"example.com/signatures.Twice.func1 -> y":   {"index": 0},
+		"example.com/signatures.Ignore -> _":        {"index": 1},
+	})
+	expectEdges(t, backend, "Results", map[string]map[string]interface{}{
+		"example.com/signatures.Divide -> quot": {"index": 0},
+		"example.com/signatures.Divide -> rem":  {"index": 1},
+	})
+	// the receiver isn't a parameter
+	unexpectEdges(t, backend, "Params", "example.com/signatures.(*Calc).Apply -> c")
+
+	// unnamed ones still get a variable each
+	for symbol, want := range map[string][2]int{
+		"example.com/signatures.Ignore":        {2, 1},
+		"example.com/signatures.(*Calc).Apply": {2, 1},
+		"example.com/signatures.Twice.func1":   {1, 1},
+	} {
+		got := [2]int{
+			backend.V("function").Has("Symbol", symbol).Out("Params").Count(),
+			backend.V("function").Has("Symbol", symbol).Out("Results").Count(),
+		}
+		if got != want {
+			t.Errorf("%s has %d params and %d results, want %d and %d", symbol, got[0], got[1], want[0], want[1])
+		}
+	}
+}
+
+func TestCallArgs(t *testing.T) {
+	backend := ingest("calls")
+
+	// "label index name" for the Arg and ReturnsTo edges of each call to pair, by call site
+	got := map[string][]string{}
+	for _, call := range backend.V("function").Has("Name", "pair").In("Callee").ToList() {
+		sites := backend.From(call).Out("CallSiteStatement").ToList()
+		if len(sites) != 1 {
+			t.Fatalf("Call has %d call sites, want 1", len(sites))
+		}
+		site := vertexName(sites[0])
+		for _, label := range []string{"Arg", "ReturnsTo"} {
+			edges, err := backend.OutEdges(call, label)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, e := range edges {
+				got[site] = append(got[site], fmt.Sprintf("%s %d %s", label, e.Properties["index"], vertexName(e.Target)))
+			}
+		}
+		sort.Strings(got[site])
+	}
+
+	want := map[string][]string{
+		// constants are arguments too
+		"sum, err := pair(x, Limit)": {"Arg 0 x", "Arg 1 example.com/calls.Limit", "ReturnsTo 0 sum", "ReturnsTo 1 err"},
+		// nothing is returned to _
+		"total, _ = pair(sum, x)": {"Arg 0 sum", "Arg 1 x", "ReturnsTo 0 total"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Calls to pair have edges %q, want %q", got, want)
+	}
+}
+
+func TestInitOrder(t *testing.T) {
+	backend := ingest("initorder")
+
+	expectEdges(t, backend, "Globals", map[string]map[string]interface{}{
+		"example.com/initorder -> A":     nil,
+		"example.com/initorder -> B":     nil,
+		"example.com/initorder -> unset": nil,
+		"example.com/initorder -> x":     nil,
+		"example.com/initorder -> y":     nil,
+	})
+	// B comes first since A depends on it, and x and y share one initializer
+	expectEdges(t, backend, "Initializers", map[string]map[string]interface{}{
+		"example.com/initorder -> B = compute()": {"index": 0},
+		"example.com/initorder -> A = B + 1":     {"index": 1},
+		"example.com/initorder -> x, y = pair()": {"index": 2},
+	})
+	if n := len(edgesLabelled(t, backend, "Initializers")); n != 3 {
+		t.Errorf("%d Initializers edges, want 3", n)
+	}
+	// init functions run in file order
+	expectEdges(t, backend, "Inits", map[string]map[string]interface{}{
+		"example.com/initorder -> example.com/initorder.init.0": {"index": 0},
+		"example.com/initorder -> example.com/initorder.init.1": {"index": 1},
+	})
+	expectEdges(t, backend, "Contains", map[string]map[string]interface{}{
+		"a.go -> example.com/initorder.init.0": nil,
+		"b.go -> example.com/initorder.init.1": nil,
+		"a.go -> A = B + 1":                    nil,
+	})
+}
+
+func TestDirectives(t *testing.T) {
+	backend := ingest("directives")
+
+	expectEdges(t, backend, "Directives", map[string]map[string]interface{}{
+		"example.com/directives.Add -> noinline": nil,
+		"example.com/directives.fast -> nosplit": nil,
+		"example.com/directives.fast -> norace":  nil,
+		// a //go:linkname can be anywhere in the file, not just in the function's doc
+		"example.com/directives.nanotime -> linkname": nil,
+	})
+	if n := backend.V("function").Has("Name", "Plain").Out("Directives").Count(); n != 0 {
+		t.Errorf("Plain has %d directives, want none", n)
+	}
+	linknames := backend.V("function").Has("Name", "nanotime").Out("Directives").ToList()
+	if len(linknames) != 1 || linknames[0].(*schema.Directive).Args != "nanotime runtime.nanotime" {
+		t.Errorf("nanotime directives = %v, want linkname to runtime.nanotime", linknames)
+	}
+
+	functions := map[string]*schema.Function{}
+	for _, v := range backend.V("function").ToList() {
+		functions[v.(*schema.Function).Name] = v.(*schema.Function)
+	}
+	add := functions["Add"]
+	if add == nil {
+		t.Fatal("No function Add")
+	}
+	// directives are left out of the doc comment
+	if want := "Add adds a and b.\n\nDeprecated: use + instead.\n"; add.Doc != want {
+		t.Errorf("Add has doc %q, want %q", add.Doc, want)
+	}
+	if got := [2]int{add.StartLine, add.EndLine}; got != [2]int{12, 14} {
+		t.Errorf("Add spans lines %v, want 12 to 14", got)
+	}
+	if f := functions["nanotime"]; f == nil || f.Doc != "" || f.StartLine != 20 || f.EndLine != 20 {
+		t.Errorf("nanotime = %+v, want no doc on line 20", f)
+	}
+}
+
+func TestSpawnsAndDefers(t *testing.T) {
+	backend := ingest("spawns")
+
+	// "kind site -> callee" for each of Run's calls with label
+	calls := func(label string) []string {
+		got := []string{}
+		run := backend.V("function").Has("Symbol", "example.com/spawns.Run").ToList()
+		for _, fc := range backend.From(run...).Out(label).ToList() {
+			for _, site := range backend.From(fc).Out("CallSiteStatement").ToList() {
+				for _, callee := range backend.From(fc).Out("Callee").ToList() {
+					got = append(got, fmt.Sprintf("%s %s -> %s", fc.(*schema.FunctionCall).Kind, vertexName(site), vertexName(callee)))
+				}
+			}
+		}
+		sort.Strings(got)
+		return got
+	}
+
+	for label, want := range map[string][]string{
+		"Spawns": {
+			"go go func() {\n\t\twork(3)\n\t}() -> example.com/spawns.Run.func1",
+			"go go work(1) -> example.com/spawns.work",
+		},
+		"Defers": {
+			"defer defer cleanup() -> example.com/spawns.cleanup",
+			"defer defer func() {}() -> example.com/spawns.Run.func2",
+		},
+		// every call is still a call, whatever its kind
+		"Calls": {
+			"call work(2) -> example.com/spawns.work",
+			"defer defer cleanup() -> example.com/spawns.cleanup",
+			"defer defer func() {}() -> example.com/spawns.Run.func2",
+			"go go func() {\n\t\twork(3)\n\t}() -> example.com/spawns.Run.func1",
+			"go go work(1) -> example.com/spawns.work",
+		},
+	} {
+		if got := calls(label); !reflect.DeepEqual(got, want) {
+			t.Errorf("Run's %s = %q, want %q", label, got, want)
+		}
+	}
+
+	// a call inside the spawned literal is a plain call of the literal's
+	expectEdges(t, backend, "Calls", map[string]map[string]interface{}{
+		"example.com/spawns.Run.func1 -> functioncall": nil,
+	})
+	if n := backend.V("function").Has("Symbol", "example.com/spawns.Run.func1").Out("Spawns").Count(); n != 0 {
+		t.Errorf("Run.func1 spawns %d calls, want none", n)
+	}
+}
+
+func TestChannels(t *testing.T) {
+	backend := ingest("channels")
+
+	expectEdges(t, backend, "Sends", map[string]map[string]interface{}{
+		"out <- i -> out": nil,
+	})
+	expectEdges(t, backend, "Receives", map[string]map[string]interface{}{
+		// including from struct fields, and ranging over a channel
+		"j := <-w.jobs -> jobs": nil,
+		"<-done -> done":        nil,
+		"in -> in":              nil,
+	})
+	expectEdges(t, backend, "Closes", map[string]map[string]interface{}{
+		"close(out) -> out": nil,
+	})
+	unexpectEdges(t, backend, "Receives", "out <- i -> out", "close(out) -> out")
+
+	// the edges out of the select say which case each one is
+	expectEdges(t, backend, "Next", map[string]map[string]interface{}{
+		"<-done -> j":         {"selectCase": "j := <-w.jobs"},
+		"<-done -> return -1": {"selectCase": "<-done"},
+		"<-done -> return 0":  {"selectCase": "default"},
+		"j -> return j":       {"selectCase": ""},
+	})
+	cases := 0
+	for _, props := range edgesLabelled(t, backend, "Next") {
+		if props["selectCase"] != "" {
+			cases++
+		}
+	}
+	if cases != 3 {
+		t.Errorf("%d Next edges with a selectCase, want 3", cases)
+	}
+}
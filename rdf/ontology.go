@@ -0,0 +1,133 @@
+package rdf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/kallsyms/go-graph/schema"
+)
+
+// ClassName is the ontology class for vertices with the given label, named after the schema type (e.g. FunctionCall)
+func ClassName(label string) string {
+	v, err := schema.NewVertex(label)
+	if err != nil {
+		return strings.ToUpper(label[:1]) + label[1:]
+	}
+	return reflect.TypeOf(v).Elem().Name()
+}
+
+// PropertyName is the ontology property for an edge label or vertex property, in lowerCamelCase so it can't clash with
+// class names (e.g. the Statement edge is gg:statement, statement vertices are gg:Statement)
+func PropertyName(name string) string {
+	// lower the whole leading run of capitals, apart from the start of the next word (ASTType -> astType)
+	n := 0
+	for n < len(name) && 'A' <= name[n] && name[n] <= 'Z' {
+		n++
+	}
+	if n > 1 && n < len(name) {
+		n--
+	}
+	return strings.ToLower(name[:n]) + name[n:]
+}
+
+func xsdType(v interface{}) string {
+	switch v.(type) {
+	case bool:
+		return "xsd:boolean"
+	case int, int64:
+		return "xsd:integer"
+	case float64:
+		return "xsd:double"
+	default:
+		return "xsd:string"
+	}
+}
+
+func classList(labels []string) string {
+	classes := []string{}
+	for _, l := range labels {
+		classes = append(classes, "gg:"+ClassName(l))
+	}
+	return unionOf(classes)
+}
+
+func unionOf(classes []string) string {
+	if len(classes) == 1 {
+		return classes[0]
+	}
+	return fmt.Sprintf("[ a owl:Class ; owl:unionOf ( %s ) ]", strings.Join(classes, " "))
+}
+
+// WriteOntology writes the vocabulary used by Build as Turtle: a class per vertex label, an object property per edge
+// label (plus sub-properties for boolean edge properties) and a datatype property per vertex property and other edge
+// property (which are found on reified edges).
+// It's generated from the schema definitions, so it always matches what Build produces.
+func WriteOntology(out io.Writer) error {
+	w := bufio.NewWriter(out)
+	writePrefixes(w)
+
+	fmt.Fprintf(w, "<%s> a owl:Ontology ;\n", strings.TrimSuffix(NS, "#"))
+	fmt.Fprintf(w, "    rdfs:label \"go-graph\" ;\n")
+	fmt.Fprintf(w, "    rdfs:comment \"Vocabulary for Go code stored by go-graph (schema version %d).\" ;\n", schema.SchemaVersion)
+	fmt.Fprintf(w, "    owl:versionInfo \"%d\" .\n\n", schema.SchemaVersion)
+
+	// vertex and edge properties, and the classes which have them
+	propClasses := map[string][]string{}
+	propTypes := map[string]interface{}{}
+
+	for _, label := range schema.VertexLabels {
+		fmt.Fprintf(w, "gg:%s a owl:Class ;\n", ClassName(label))
+		fmt.Fprintf(w, "    rdfs:label %s .\n\n", escapeLiteral(label))
+
+		v, err := schema.NewVertex(label)
+		if err != nil {
+			return err
+		}
+		for k, zero := range v.Properties() {
+			propClasses[k] = append(propClasses[k], "gg:"+ClassName(label))
+			propTypes[k] = zero
+		}
+	}
+
+	for _, def := range schema.EdgeDefinitions {
+		fmt.Fprintf(w, "gg:%s a owl:ObjectProperty ;\n", PropertyName(def.Label))
+		fmt.Fprintf(w, "    rdfs:label %s ;\n", escapeLiteral(def.Label))
+		fmt.Fprintf(w, "    rdfs:domain %s ;\n", classList(def.From))
+		fmt.Fprintf(w, "    rdfs:range %s .\n\n", classList(def.To))
+
+		flags := []string{}
+		for k, zero := range def.Properties {
+			if _, ok := zero.(bool); ok {
+				flags = append(flags, k)
+			} else if !slices.Contains(propClasses[k], "rdf:Statement") {
+				propClasses[k] = append(propClasses[k], "rdf:Statement")
+				propTypes[k] = zero
+			}
+		}
+		sort.Strings(flags)
+		for _, k := range flags {
+			fmt.Fprintf(w, "gg:%s a owl:ObjectProperty ;\n", flagPredicate(def.Label, k))
+			fmt.Fprintf(w, "    rdfs:subPropertyOf gg:%s ;\n", PropertyName(def.Label))
+			fmt.Fprintf(w, "    rdfs:comment %s .\n\n", escapeLiteral(fmt.Sprintf("A %s edge with %s set.", def.Label, k)))
+		}
+	}
+
+	props := make([]string, 0, len(propClasses))
+	for k := range propClasses {
+		props = append(props, k)
+	}
+	sort.Strings(props)
+	for _, k := range props {
+		fmt.Fprintf(w, "gg:%s a owl:DatatypeProperty ;\n", PropertyName(k))
+		fmt.Fprintf(w, "    rdfs:label %s ;\n", escapeLiteral(k))
+		fmt.Fprintf(w, "    rdfs:domain %s ;\n", unionOf(propClasses[k]))
+		fmt.Fprintf(w, "    rdfs:range %s .\n\n", xsdType(propTypes[k]))
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,624 @@
+// Package rdf exports the stored graph as RDF (N-Triples or Turtle), for loading into a SPARQL triple store.
+//
+// Vertices become resources typed with a class from the go-graph ontology (see WriteOntology), their properties become
+// literals, and every edge label becomes a predicate (in lowerCamelCase, e.g. Calls is gg:calls). Vertices get IRIs
+// made from their package path, version and position, under a configurable base which defaults to pkg.go.dev (so
+// package and function IRIs actually resolve):
+//
+//	package       <base>example.com/mod@v1.2.3
+//	function      <base>example.com/mod@v1.2.3/sub/pkg#T.Name
+//	statement     <base>example.com/mod@v1.2.3/sub/file.go#123   (byte offset in the file)
+//	function call <statement IRI of the call site>/call/example.com/callee.Symbol
+//	variable      <statement IRI of its first use>/var/name
+//
+// Vertices which can't be placed anywhere (e.g. variables which are never used) become blank nodes.
+//
+// A triple can't carry properties, so edges with boolean properties set also get a sub-property triple (e.g. a back
+// edge is both gg:next and gg:nextBackEdge), and edges with any other properties (like the index of gg:arg) are also
+// written as a reified rdf:Statement, holding those properties:
+//
+//	_:e1 a rdf:Statement ; rdf:subject <call> ; rdf:predicate gg:arg ; rdf:object <variable> ; gg:index 1 .
+package rdf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	gbackend "github.com/kallsyms/go-graph/backend"
+	"github.com/kallsyms/go-graph/schema"
+)
+
+const NS = "https://github.com/kallsyms/go-graph/ontology#"
+const DEFAULT_BASE = "https://pkg.go.dev/"
+
+const RDF_NS = "http://www.w3.org/1999/02/22-rdf-syntax-ns#"
+const RDFS_NS = "http://www.w3.org/2000/01/rdf-schema#"
+const XSD_NS = "http://www.w3.org/2001/XMLSchema#"
+const OWL_NS = "http://www.w3.org/2002/07/owl#"
+
+// triple holds already serialized terms
+type triple struct {
+	s, p, o string
+}
+
+// Graph is the RDF form of everything in a backend, ready to be written out
+type Graph struct {
+	triples []triple
+}
+
+func iri(s string) string {
+	return "<" + s + ">"
+}
+
+// escapeIRI percent-encodes everything in s that isn't safe to put in an IRI as-is (keeping / and @ for paths)
+func escapeIRI(s string) string {
+	var b strings.Builder
+	for _, c := range []byte(s) {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+			b.WriteByte(c)
+		case strings.IndexByte("-._~/@", c) >= 0:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func escapeLiteral(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\r", `\r`, "\t", `\t`)
+	// statement text is cut off at a byte limit, which can split a rune
+	return `"` + r.Replace(strings.ToValidUTF8(s, "\uFFFD")) + `"`
+}
+
+func literal(v interface{}) string {
+	switch v := v.(type) {
+	case string:
+		return escapeLiteral(v)
+	case bool:
+		return fmt.Sprintf(`"%t"^^<%sboolean>`, v, XSD_NS)
+	case int, int64:
+		return fmt.Sprintf(`"%d"^^<%sinteger>`, v, XSD_NS)
+	case float64:
+		return fmt.Sprintf(`"%v"^^<%sdouble>`, v, XSD_NS)
+	default:
+		return escapeLiteral(fmt.Sprint(v))
+	}
+}
+
+// commonDir is the longest directory prefix shared by all of files
+func commonDir(files []string) string {
+	if len(files) == 0 {
+		return ""
+	}
+	dir := path.Dir(files[0])
+	for _, f := range files[1:] {
+		for dir != "/" && dir != "." && !strings.HasPrefix(f, dir+"/") {
+			dir = path.Dir(dir)
+		}
+	}
+	return dir
+}
+
+// iriAllocator hands out unique IRIs, suffixing ~2, ~3, ... if the same IRI is asked for twice
+type iriAllocator struct {
+	used map[string]bool
+}
+
+func (a *iriAllocator) alloc(want string) string {
+	got := want
+	for i := 2; a.used[got]; i++ {
+		got = fmt.Sprintf("%s~%d", want, i)
+	}
+	a.used[got] = true
+	return got
+}
+
+type candidate struct {
+	v   schema.Vertex
+	iri string
+	// tie breaker so that suffixes are handed out the same way every run
+	key string
+}
+
+// allocAll assigns IRIs to cands in a stable order
+func (a *iriAllocator) allocAll(cands []candidate, iris map[interface{}]string) {
+	sort.Slice(cands, func(i, j int) bool {
+		if cands[i].iri != cands[j].iri {
+			return cands[i].iri < cands[j].iri
+		}
+		return cands[i].key < cands[j].key
+	})
+	for _, c := range cands {
+		iris[c.v.GetBackendMeta()] = a.alloc(c.iri)
+	}
+}
+
+func sortKey(v schema.Vertex) string {
+	props := v.Properties()
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := []string{}
+	for _, k := range keys {
+		parts = append(parts, fmt.Sprintf("%s=%v", k, props[k]))
+	}
+	return strings.Join(parts, "\x00")
+}
+
+// symbolIRISuffix is the part of a function or type's IRI after its package's, the same as pkg.go.dev's: "/sub/pkg#T.M"
+// for the method example.com/mod/sub/pkg.(*T).M in the module example.com/mod
+func symbolIRISuffix(pkg *schema.Package, symbol, name string) string {
+	if symbol == "" {
+		return "#" + escapeIRI(name)
+	}
+	if !strings.HasPrefix(symbol, pkg.SourceURL) {
+		// e.g. type arguments, see schema.Type
+		return "#" + escapeIRI(symbol)
+	}
+
+	// the import path ends at the first dot after its last slash
+	rest := strings.TrimPrefix(symbol, pkg.SourceURL)
+	dir := ""
+	if slash := strings.LastIndex(strings.SplitN(rest, "(", 2)[0], "/"); slash >= 0 {
+		if dot := strings.Index(rest[slash:], "."); dot >= 0 {
+			dir = rest[:slash+dot]
+		}
+	}
+	local := strings.TrimPrefix(strings.TrimPrefix(rest, dir), ".")
+	local = strings.NewReplacer("(*", "", ")", "").Replace(local)
+
+	return escapeIRI(dir) + "#" + escapeIRI(local)
+}
+
+// sigPosition is where a variable is in a function's signature
+type sigPosition struct {
+	// the function, or the generic type for type parameters
+	owner schema.Vertex
+	// param, result or typeparam
+	kind  string
+	index int
+}
+
+// intProperty reads back an integer property, which some backends return as a float
+func intProperty(v interface{}) (int, bool) {
+	switch v := v.(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
+
+func stmtBefore(a, b *schema.Statement) bool {
+	if a.File != b.File {
+		return a.File < b.File
+	}
+	return a.Offset < b.Offset
+}
+
+// Build reads everything in src and works out the IRI of every vertex.
+// base is prepended to every vertex IRI, DEFAULT_BASE is used if it's empty.
+func Build(src gbackend.Enumerator, base string) (*Graph, error) {
+	if base == "" {
+		base = DEFAULT_BASE
+	}
+
+	vertices := []schema.Vertex{}
+	byMeta := map[interface{}]schema.Vertex{}
+	err := src.ScanVertices(func(v schema.Vertex) error {
+		vertices = append(vertices, v)
+		byMeta[v.GetBackendMeta()] = v
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error reading vertices: %v", err)
+	}
+
+	edges := []schema.Edge{}
+	err = src.ScanEdges(func(e schema.Edge) error {
+		edges = append(edges, e)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error reading edges: %v", err)
+	}
+
+	// work out where everything lives from the edges
+	pkgOf := map[interface{}]*schema.Package{}
+	ownerOf := map[interface{}]*schema.Type{}
+	fileOf := map[interface{}]*schema.File{}
+	funcOf := map[interface{}]*schema.Function{}
+	directiveOf := map[interface{}]*schema.Function{}
+	siteOf := map[interface{}]*schema.Statement{}
+	callerOf := map[interface{}]*schema.Function{}
+	calleeOf := map[interface{}]*schema.Function{}
+	firstUse := map[interface{}]*schema.Statement{}
+	sigOf := map[interface{}]sigPosition{}
+	for _, e := range edges {
+		src := byMeta[e.Source.GetBackendMeta()]
+		dst := byMeta[e.Target.GetBackendMeta()]
+		if src == nil || dst == nil {
+			return nil, fmt.Errorf("%q edge between unknown vertices %v and %v", e.Label, e.Source.GetBackendMeta(), e.Target.GetBackendMeta())
+		}
+
+		switch e.Label {
+		case "Functions", "Types", "Files", "Constants":
+			pkgOf[dst.GetBackendMeta()], _ = src.(*schema.Package)
+		case "Contains":
+			fileOf[dst.GetBackendMeta()], _ = src.(*schema.File)
+		case "Fields":
+			ownerOf[dst.GetBackendMeta()], _ = src.(*schema.Type)
+		case "Statement":
+			funcOf[dst.GetBackendMeta()], _ = src.(*schema.Function)
+		case "Directives":
+			directiveOf[dst.GetBackendMeta()], _ = src.(*schema.Function)
+		case "Calls":
+			callerOf[dst.GetBackendMeta()], _ = src.(*schema.Function)
+		case "Callee":
+			calleeOf[src.GetBackendMeta()], _ = dst.(*schema.Function)
+		case "CallSiteStatement":
+			siteOf[src.GetBackendMeta()], _ = dst.(*schema.Statement)
+		case "Params", "Results", "TypeParams":
+			index, hasIndex := intProperty(e.Properties["index"])
+			if hasIndex {
+				sigOf[dst.GetBackendMeta()] = sigPosition{src, strings.ToLower(strings.TrimSuffix(e.Label, "s")), index}
+			}
+		case "References", "Assigns":
+			stmt, ok := src.(*schema.Statement)
+			if !ok {
+				continue
+			}
+			if prev := firstUse[dst.GetBackendMeta()]; prev == nil || stmtBefore(stmt, prev) {
+				firstUse[dst.GetBackendMeta()] = stmt
+			}
+		}
+	}
+
+	stmtPkg := func(stmt schema.Vertex) *schema.Package {
+		if fn := funcOf[stmt.GetBackendMeta()]; fn != nil {
+			return pkgOf[fn.GetBackendMeta()]
+		}
+		return nil
+	}
+
+	// statement files are absolute paths on whichever machine did the ingest, so make them relative to the package
+	pkgFiles := map[*schema.Package][]string{}
+	for _, v := range vertices {
+		if stmt, ok := v.(*schema.Statement); ok {
+			if pkg := stmtPkg(stmt); pkg != nil {
+				pkgFiles[pkg] = append(pkgFiles[pkg], stmt.File)
+			}
+		}
+	}
+	pkgRoot := map[*schema.Package]string{}
+	for pkg, files := range pkgFiles {
+		pkgRoot[pkg] = commonDir(files)
+	}
+
+	alloc := &iriAllocator{used: map[string]bool{}}
+	iris := map[interface{}]string{}
+	byLabel := map[string][]schema.Vertex{}
+	for _, v := range vertices {
+		byLabel[v.Label()] = append(byLabel[v.Label()], v)
+	}
+
+	// each kind of vertex is placed relative to ones handled before it
+	cands := []candidate{}
+	for _, v := range byLabel["package"] {
+		pkg := v.(*schema.Package)
+		name := escapeIRI(pkg.SourceURL)
+		if pkg.Version != "" {
+			name += "@" + escapeIRI(pkg.Version)
+		}
+		cands = append(cands, candidate{v, base + name, sortKey(v)})
+	}
+	alloc.allocAll(cands, iris)
+
+	cands = []candidate{}
+	for _, v := range byLabel["function"] {
+		if pkg := pkgOf[v.GetBackendMeta()]; pkg != nil {
+			f := v.(*schema.Function)
+			cands = append(cands, candidate{v, iris[pkg.GetBackendMeta()] + symbolIRISuffix(pkg, f.Symbol, f.Name), sortKey(v)})
+		}
+	}
+	alloc.allocAll(cands, iris)
+
+	cands = []candidate{}
+	for _, v := range byLabel["type"] {
+		if pkg := pkgOf[v.GetBackendMeta()]; pkg != nil {
+			t := v.(*schema.Type)
+			cands = append(cands, candidate{v, iris[pkg.GetBackendMeta()] + symbolIRISuffix(pkg, t.Symbol, t.Name), sortKey(v)})
+		}
+	}
+	alloc.allocAll(cands, iris)
+
+	// type parameters are placed by position, like parameters
+	cands = []candidate{}
+	for _, v := range byLabel["typeparam"] {
+		if sig, ok := sigOf[v.GetBackendMeta()]; ok && iris[sig.owner.GetBackendMeta()] != "" {
+			cands = append(cands, candidate{v, fmt.Sprintf("%s/%s/%d", iris[sig.owner.GetBackendMeta()], sig.kind, sig.index), sortKey(v)})
+		}
+	}
+	alloc.allocAll(cands, iris)
+
+	// fields of anonymous structs have no owner, and end up as blank nodes
+	cands = []candidate{}
+	for _, v := range byLabel["field"] {
+		if owner := ownerOf[v.GetBackendMeta()]; owner != nil && iris[owner.GetBackendMeta()] != "" {
+			cands = append(cands, candidate{v, iris[owner.GetBackendMeta()] + "." + escapeIRI(v.(*schema.Field).Name), sortKey(v)})
+		}
+	}
+	alloc.allocAll(cands, iris)
+
+	cands = []candidate{}
+	for _, v := range byLabel["directive"] {
+		if fn := directiveOf[v.GetBackendMeta()]; fn != nil && iris[fn.GetBackendMeta()] != "" {
+			cands = append(cands, candidate{v, iris[fn.GetBackendMeta()] + "/go:" + escapeIRI(v.(*schema.Directive).Name), sortKey(v)})
+		}
+	}
+	alloc.allocAll(cands, iris)
+
+	// all of a package's files are in the same directory
+	cands = []candidate{}
+	for _, v := range byLabel["file"] {
+		if pkg := pkgOf[v.GetBackendMeta()]; pkg != nil {
+			cands = append(cands, candidate{v, iris[pkg.GetBackendMeta()] + "/" + escapeIRI(path.Base(v.(*schema.File).Path)), sortKey(v)})
+		}
+	}
+	alloc.allocAll(cands, iris)
+
+	cands = []candidate{}
+	for _, v := range byLabel["statement"] {
+		stmt := v.(*schema.Statement)
+		if file := fileOf[v.GetBackendMeta()]; file != nil && iris[file.GetBackendMeta()] != "" {
+			cands = append(cands, candidate{v, fmt.Sprintf("%s#%d", iris[file.GetBackendMeta()], stmt.Offset), sortKey(v)})
+			continue
+		}
+
+		// no file vertex, so go by the statement's absolute path
+		pkg := stmtPkg(stmt)
+		if pkg == nil {
+			continue
+		}
+		file := strings.TrimPrefix(strings.TrimPrefix(stmt.File, pkgRoot[pkg]), "/")
+		cands = append(cands, candidate{v, fmt.Sprintf("%s/%s#%d", iris[pkg.GetBackendMeta()], escapeIRI(file), stmt.Offset), sortKey(v)})
+	}
+	alloc.allocAll(cands, iris)
+
+	cands = []candidate{}
+	for _, v := range byLabel["functioncall"] {
+		meta := v.GetBackendMeta()
+		callee := calleeOf[meta]
+		if callee == nil {
+			continue
+		}
+
+		var at string
+		if site := siteOf[meta]; site != nil {
+			at = iris[site.GetBackendMeta()]
+		} else if caller := callerOf[meta]; caller != nil {
+			at = iris[caller.GetBackendMeta()]
+		}
+		if at == "" {
+			continue
+		}
+		calleeName := callee.Symbol
+		if calleeName == "" {
+			calleeName = callee.Name
+		}
+		cands = append(cands, candidate{v, at + "/call/" + escapeIRI(calleeName), sortKey(v)})
+	}
+	alloc.allocAll(cands, iris)
+
+	cands = []candidate{}
+	for _, v := range byLabel["variable"] {
+		// parameters and results are placed by position, which also keeps their order in the IRI
+		if sig, ok := sigOf[v.GetBackendMeta()]; ok && iris[sig.owner.GetBackendMeta()] != "" {
+			cands = append(cands, candidate{v, fmt.Sprintf("%s/%s/%d", iris[sig.owner.GetBackendMeta()], sig.kind, sig.index), sortKey(v)})
+			continue
+		}
+
+		use := firstUse[v.GetBackendMeta()]
+		if use == nil || iris[use.GetBackendMeta()] == "" {
+			continue
+		}
+		cands = append(cands, candidate{v, iris[use.GetBackendMeta()] + "/var/" + escapeIRI(v.(*schema.Variable).Name), sortKey(v)})
+	}
+	alloc.allocAll(cands, iris)
+
+	// package level constants are placed like types, local ones like variables
+	cands = []candidate{}
+	for _, v := range byLabel["constant"] {
+		c := v.(*schema.Constant)
+		if pkg := pkgOf[v.GetBackendMeta()]; pkg != nil {
+			cands = append(cands, candidate{v, iris[pkg.GetBackendMeta()] + symbolIRISuffix(pkg, c.Symbol, c.Name), sortKey(v)})
+		} else if use := firstUse[v.GetBackendMeta()]; use != nil && iris[use.GetBackendMeta()] != "" {
+			cands = append(cands, candidate{v, iris[use.GetBackendMeta()] + "/const/" + escapeIRI(c.Name), sortKey(v)})
+		}
+	}
+	alloc.allocAll(cands, iris)
+
+	for meta, s := range iris {
+		iris[meta] = iri(s)
+	}
+	// anything not placed by now is a blank node
+	term := func(v schema.Vertex) string {
+		meta := v.GetBackendMeta()
+		if s, ok := iris[meta]; ok {
+			return s
+		}
+		s := fmt.Sprintf("_:b%d", len(iris))
+		iris[meta] = s
+		return s
+	}
+
+	g := &Graph{}
+	for _, v := range vertices {
+		s := term(v)
+		g.triples = append(g.triples, triple{s, iri(RDF_NS + "type"), iri(NS + ClassName(v.Label()))})
+
+		props := v.Properties()
+		keys := make([]string, 0, len(props))
+		for k := range props {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			g.triples = append(g.triples, triple{s, iri(NS + PropertyName(k)), literal(props[k])})
+		}
+	}
+
+	reified := 0
+	for _, e := range edges {
+		s := term(byMeta[e.Source.GetBackendMeta()])
+		p := iri(NS + PropertyName(e.Label))
+		o := term(byMeta[e.Target.GetBackendMeta()])
+		g.triples = append(g.triples, triple{s, p, o})
+
+		// edge properties can't hang off of a plain triple, so true flags get their own sub-property instead...
+		for _, k := range sortedFlags(e.Properties) {
+			g.triples = append(g.triples, triple{s, iri(NS + flagPredicate(e.Label, k)), o})
+		}
+
+		// ...and everything else (e.g. argument indexes) goes on a reified copy of the triple
+		values := edgeValues(e.Properties)
+		if len(values) == 0 {
+			continue
+		}
+		reified++
+		r := fmt.Sprintf("_:e%d", reified)
+		g.triples = append(g.triples,
+			triple{r, iri(RDF_NS + "type"), iri(RDF_NS + "Statement")},
+			triple{r, iri(RDF_NS + "subject"), s},
+			triple{r, iri(RDF_NS + "predicate"), p},
+			triple{r, iri(RDF_NS + "object"), o},
+		)
+		for _, k := range values {
+			g.triples = append(g.triples, triple{r, iri(NS + PropertyName(k)), literal(e.Properties[k])})
+		}
+	}
+
+	return g, nil
+}
+
+// edgeValues returns the (sorted) non-boolean properties in props which are worth keeping, leaving out empty strings
+// (e.g. the selectCase of Next edges not out of a select)
+func edgeValues(props map[string]interface{}) []string {
+	keys := []string{}
+	for k, v := range props {
+		switch v := v.(type) {
+		case bool:
+			continue
+		case string:
+			if v == "" {
+				continue
+			}
+		}
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func sortedFlags(props map[string]interface{}) []string {
+	flags := []string{}
+	for k, v := range props {
+		if b, ok := v.(bool); ok && b {
+			flags = append(flags, k)
+		}
+	}
+	sort.Strings(flags)
+	return flags
+}
+
+// flagPredicate names the sub-property used for edges with the boolean property prop set, e.g. Next + isBackEdge is
+// nextBackEdge
+func flagPredicate(label, prop string) string {
+	prop = strings.TrimPrefix(prop, "is")
+	if prop == "" {
+		return PropertyName(label)
+	}
+	return PropertyName(label) + strings.ToUpper(prop[:1]) + prop[1:]
+}
+
+// WriteNTriples writes g as N-Triples, one triple per line
+func (g *Graph) WriteNTriples(out io.Writer) error {
+	w := bufio.NewWriter(out)
+	for _, t := range g.triples {
+		fmt.Fprintf(w, "%s %s %s .\n", t.s, t.p, t.o)
+	}
+	return w.Flush()
+}
+
+// shorten turns IRIs (and literal datatypes) in the well known namespaces into prefixed names
+func shorten(term string) string {
+	if strings.HasPrefix(term, `"`) {
+		if i := strings.LastIndex(term, "^^<"+XSD_NS); i >= 0 {
+			return term[:i] + "^^xsd:" + strings.TrimSuffix(term[i+len("^^<"+XSD_NS):], ">")
+		}
+		return term
+	}
+	for prefix, ns := range map[string]string{"gg": NS, "rdf": RDF_NS, "rdfs": RDFS_NS, "xsd": XSD_NS} {
+		if strings.HasPrefix(term, "<"+ns) {
+			local := strings.TrimSuffix(strings.TrimPrefix(term, "<"+ns), ">")
+			if local != "" && !strings.ContainsAny(local, "/#%.~") {
+				return prefix + ":" + local
+			}
+		}
+	}
+	return term
+}
+
+func writePrefixes(w io.Writer) {
+	fmt.Fprintf(w, "@prefix gg: <%s> .\n", NS)
+	fmt.Fprintf(w, "@prefix rdf: <%s> .\n", RDF_NS)
+	fmt.Fprintf(w, "@prefix rdfs: <%s> .\n", RDFS_NS)
+	fmt.Fprintf(w, "@prefix xsd: <%s> .\n", XSD_NS)
+	fmt.Fprintf(w, "@prefix owl: <%s> .\n", OWL_NS)
+	fmt.Fprintf(w, "\n")
+}
+
+// WriteTurtle writes g as Turtle, grouping each subject's triples together
+func (g *Graph) WriteTurtle(out io.Writer) error {
+	w := bufio.NewWriter(out)
+	writePrefixes(w)
+
+	order := []string{}
+	bySubject := map[string][]triple{}
+	for _, t := range g.triples {
+		if _, ok := bySubject[t.s]; !ok {
+			order = append(order, t.s)
+		}
+		bySubject[t.s] = append(bySubject[t.s], t)
+	}
+
+	for _, s := range order {
+		fmt.Fprintf(w, "%s", s)
+		for i, t := range bySubject[s] {
+			p := shorten(t.p)
+			if p == "rdf:type" {
+				p = "a"
+			}
+			sep := " ;\n   "
+			if i == 0 {
+				sep = ""
+			}
+			fmt.Fprintf(w, "%s %s %s", sep, p, shorten(t.o))
+		}
+		fmt.Fprintf(w, " .\n\n")
+	}
+
+	return w.Flush()
+}
@@ -0,0 +1,154 @@
+package rdf
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+
+	gbackend "github.com/kallsyms/go-graph/backend"
+	"github.com/kallsyms/go-graph/coordination"
+	"github.com/kallsyms/go-graph/schema"
+)
+
+// testGraph stores a package with a file and a function Run, whose first statement calls Log(msg) and loops back on
+// itself
+func testGraph(t *testing.T) *gbackend.MemoryBackend {
+	backend := gbackend.NewMemoryBackend()
+	pkg, err := backend.CreatePackage(coordination.PackageTuple{Name: "example.com/p", Version: "v1.0.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	file := &schema.File{Path: "p.go", Package: "example.com/p", Module: "example.com/p"}
+	run := &schema.Function{Name: "Run", Symbol: "example.com/p.Run"}
+	log := &schema.Function{Name: "Log", Symbol: "example.com/p.Log"}
+	s0 := &schema.Statement{File: "/src/p.go", Offset: 40, Text: "Log(msg)"}
+	s1 := &schema.Statement{File: "/src/p.go", Offset: 52, Text: "goto loop"}
+	call := &schema.FunctionCall{Kind: "call"}
+	msg := &schema.Variable{Name: "msg", Type: "string"}
+
+	vertices := make(chan schema.Vertex)
+	stream := backend.AddVStream(vertices, func([]schema.Vertex) {})
+	for _, v := range []schema.Vertex{file, run, log, s0, s1, call, msg} {
+		vertices <- v
+	}
+	close(vertices)
+	if err := stream.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	next := func(from, to *schema.Statement, back bool) schema.Edge {
+		return schema.Edge{Source: from, Label: "Next", Target: to, Properties: map[string]interface{}{"isBackEdge": back, "selectCase": ""}}
+	}
+	edges := []schema.Edge{
+		{Source: pkg, Label: "Files", Target: file},
+		{Source: pkg, Label: "Functions", Target: run},
+		{Source: pkg, Label: "Functions", Target: log},
+		{Source: file, Label: "Contains", Target: s0},
+		{Source: file, Label: "Contains", Target: s1},
+		{Source: run, Label: "Statement", Target: s0},
+		{Source: run, Label: "Statement", Target: s1},
+		{Source: run, Label: "FirstStatement", Target: s0},
+		next(s0, s1, false),
+		next(s1, s0, true),
+		{Source: run, Label: "Calls", Target: call},
+		{Source: call, Label: "Callee", Target: log},
+		{Source: call, Label: "CallSiteStatement", Target: s0},
+		{Source: call, Label: "Arg", Target: msg, Properties: map[string]interface{}{"index": 1}},
+	}
+	if err := backend.AddEBulk(edges, func([]schema.Edge) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	return backend
+}
+
+var update = flag.Bool("update", false, "rewrite testdata/p.ttl with the current output")
+
+func TestWriteTurtle(t *testing.T) {
+	g, err := Build(testGraph(t), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := g.WriteTurtle(out); err != nil {
+		t.Fatal(err)
+	}
+
+	if *update {
+		if err := os.WriteFile("testdata/p.ttl", out.Bytes(), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile("testdata/p.ttl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != string(want) {
+		t.Errorf("Turtle differs from testdata/p.ttl (rerun with -update to see how):\n%s", out.String())
+	}
+}
+
+func TestWriteNTriples(t *testing.T) {
+	g, err := Build(testGraph(t), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := &bytes.Buffer{}
+	if err := g.WriteNTriples(out); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+
+	const (
+		pkg  = "<https://pkg.go.dev/example.com/p@v1.0.0>"
+		s0   = "<https://pkg.go.dev/example.com/p@v1.0.0/p.go#40>"
+		s1   = "<https://pkg.go.dev/example.com/p@v1.0.0/p.go#52>"
+		call = "<https://pkg.go.dev/example.com/p@v1.0.0/p.go#40/call/example.com/p.Log>"
+	)
+	for _, want := range []string{
+		pkg + " <" + NS + "functions> <https://pkg.go.dev/example.com/p@v1.0.0#Run> .",
+		s0 + " <" + NS + "next> " + s1 + " .",
+		s1 + " <" + NS + "next> " + s0 + " .",
+		s1 + " <" + NS + "nextBackEdge> " + s0 + " .",
+		call + " <" + NS + "callee> <https://pkg.go.dev/example.com/p@v1.0.0#Log> .",
+	} {
+		if !slices.Contains(lines, want) {
+			t.Errorf("Missing %s", want)
+		}
+	}
+	if slices.Contains(lines, s0+" <"+NS+"nextBackEdge> "+s1+" .") {
+		t.Errorf("Forward edge marked as a back edge")
+	}
+
+	// the argument index is only on the reified statement, which has to point back at the arg triple
+	statements := []string{}
+	for _, l := range lines {
+		if strings.HasSuffix(l, " <"+RDF_NS+"type> <"+RDF_NS+"Statement> .") {
+			statements = append(statements, strings.Fields(l)[0])
+		}
+	}
+	if len(statements) != 1 {
+		t.Fatalf("Expected only the Arg edge to be reified, got %v", statements)
+	}
+	r := statements[0]
+	var arg string
+	for _, l := range lines {
+		if strings.HasPrefix(l, call+" <"+NS+"arg> ") {
+			arg = strings.Fields(l)[2]
+		}
+	}
+	for _, want := range []string{
+		r + " <" + RDF_NS + "subject> " + call + " .",
+		r + " <" + RDF_NS + "predicate> <" + NS + "arg> .",
+		r + " <" + RDF_NS + "object> " + arg + " .",
+		r + " <" + NS + "index> \"1\"^^<" + XSD_NS + "integer> .",
+	} {
+		if !slices.Contains(lines, want) {
+			t.Errorf("Missing %s", want)
+		}
+	}
+}
@@ -0,0 +1,295 @@
+package schema
+
+import "fmt"
+
+// SchemaVersion must be bumped whenever vertices or edges change in a way that makes previously stored graphs (or dumps
+// of them) incompatible.
+const SchemaVersion = 18
+
+// EdgeDefinition describes which vertex labels an edge label may connect, and which properties its edges carry
+type EdgeDefinition struct {
+	Label string
+	From  []string
+	To    []string
+	// property name -> zero value of its type
+	Properties map[string]interface{}
+}
+
+// NewVertex returns an empty vertex for the given label, e.g. to unmarshal stored properties into
+func NewVertex(label string) (Vertex, error) {
+	switch label {
+	case "package":
+		return &Package{}, nil
+	case "function":
+		return &Function{}, nil
+	case "variable":
+		return &Variable{}, nil
+	case "statement":
+		return &Statement{}, nil
+	case "functioncall":
+		return &FunctionCall{}, nil
+	case "type":
+		return &Type{}, nil
+	case "field":
+		return &Field{}, nil
+	case "file":
+		return &File{}, nil
+	case "constant":
+		return &Constant{}, nil
+	case "directive":
+		return &Directive{}, nil
+	case "typeparam":
+		return &TypeParam{}, nil
+	}
+	return nil, fmt.Errorf("Unknown vertex label %q", label)
+}
+
+var VertexLabels = []string{
+	"package",
+	"function",
+	"variable",
+	"statement",
+	"functioncall",
+	"type",
+	"field",
+	"file",
+	"constant",
+	"directive",
+	"typeparam",
+}
+
+var EdgeDefinitions = []EdgeDefinition{
+	{
+		Label: "Functions",
+		From:  []string{"package"},
+		To:    []string{"function"},
+	},
+	{
+		Label: "Statement",
+		From:  []string{"function"},
+		To:    []string{"statement"},
+	},
+	{
+		Label: "Calls",
+		From:  []string{"function"},
+		To:    []string{"functioncall"},
+	},
+	{
+		Label: "Spawns",
+		From:  []string{"function"},
+		To:    []string{"functioncall"},
+	},
+	{
+		Label: "Defers",
+		From:  []string{"function"},
+		To:    []string{"functioncall"},
+	},
+	{
+		Label: "Callee",
+		From:  []string{"functioncall"},
+		To:    []string{"function"},
+	},
+	{
+		Label: "References",
+		From:  []string{"statement"},
+		To:    []string{"variable", "constant"},
+	},
+	{
+		Label: "Assigns",
+		From:  []string{"statement"},
+		To:    []string{"variable"},
+	},
+	{
+		Label: "ReadsField",
+		From:  []string{"statement"},
+		To:    []string{"field"},
+		Properties: map[string]interface{}{
+			"isPromoted": false,
+		},
+	},
+	{
+		Label: "WritesField",
+		From:  []string{"statement"},
+		To:    []string{"field"},
+		Properties: map[string]interface{}{
+			"isPromoted": false,
+		},
+	},
+	{
+		Label: "Next",
+		From:  []string{"statement"},
+		To:    []string{"statement"},
+		Properties: map[string]interface{}{
+			"isBackEdge": false,
+			// the select case taken, e.g. "v := <-ch" or "default"
+			"selectCase": "",
+		},
+	},
+	{
+		Label: "Sends",
+		From:  []string{"statement"},
+		To:    []string{"variable", "field"},
+	},
+	{
+		Label: "Receives",
+		From:  []string{"statement"},
+		To:    []string{"variable", "field"},
+	},
+	{
+		Label: "Closes",
+		From:  []string{"statement"},
+		To:    []string{"variable", "field"},
+	},
+	{
+		Label: "FirstStatement",
+		From:  []string{"function"},
+		To:    []string{"statement"},
+	},
+	{
+		Label: "Arg",
+		From:  []string{"functioncall"},
+		To:    []string{"variable", "constant", "field"},
+		Properties: map[string]interface{}{
+			"index": 0,
+		},
+	},
+	{
+		Label: "ReturnsTo",
+		From:  []string{"functioncall"},
+		To:    []string{"variable", "field"},
+		Properties: map[string]interface{}{
+			"index": 0,
+		},
+	},
+	{
+		Label: "CallSiteStatement",
+		From:  []string{"functioncall"},
+		To:    []string{"statement"},
+	},
+	{
+		Label: "Imports",
+		From:  []string{"package"},
+		To:    []string{"package"},
+	},
+	{
+		Label: "Files",
+		From:  []string{"package"},
+		To:    []string{"file"},
+	},
+	{
+		Label: "Contains",
+		From:  []string{"file"},
+		To:    []string{"function", "statement"},
+	},
+	{
+		Label: "Params",
+		From:  []string{"function"},
+		To:    []string{"variable"},
+		Properties: map[string]interface{}{
+			"index": 0,
+		},
+	},
+	{
+		Label: "Results",
+		From:  []string{"function"},
+		To:    []string{"variable"},
+		Properties: map[string]interface{}{
+			"index": 0,
+		},
+	},
+	{
+		Label: "Directives",
+		From:  []string{"function"},
+		To:    []string{"directive"},
+	},
+	{
+		Label: "Defines",
+		From:  []string{"statement"},
+		To:    []string{"function"},
+	},
+	{
+		Label: "Captures",
+		From:  []string{"function"},
+		To:    []string{"variable"},
+	},
+	{
+		Label: "Constants",
+		From:  []string{"package"},
+		To:    []string{"constant"},
+	},
+	{
+		Label: "Globals",
+		From:  []string{"package"},
+		To:    []string{"variable"},
+	},
+	{
+		Label: "Initializers",
+		From:  []string{"package"},
+		To:    []string{"statement"},
+		Properties: map[string]interface{}{
+			"index": 0,
+		},
+	},
+	{
+		Label: "Inits",
+		From:  []string{"package"},
+		To:    []string{"function"},
+		Properties: map[string]interface{}{
+			"index": 0,
+		},
+	},
+	{
+		Label: "Types",
+		From:  []string{"package"},
+		To:    []string{"type"},
+	},
+	{
+		Label: "Fields",
+		From:  []string{"type"},
+		To:    []string{"field"},
+	},
+	{
+		Label: "Methods",
+		From:  []string{"type"},
+		To:    []string{"function"},
+	},
+	{
+		Label: "Implements",
+		From:  []string{"type"},
+		To:    []string{"type"},
+		Properties: map[string]interface{}{
+			"isPointer": false,
+		},
+	},
+	{
+		Label: "HasType",
+		From:  []string{"variable", "field", "constant"},
+		To:    []string{"type"},
+		Properties: map[string]interface{}{
+			"isPointer": false,
+		},
+	},
+	{
+		Label: "TypeParams",
+		From:  []string{"function", "type"},
+		To:    []string{"typeparam"},
+		Properties: map[string]interface{}{
+			"index": 0,
+		},
+	},
+	{
+		Label: "HasConstraint",
+		From:  []string{"typeparam"},
+		To:    []string{"type"},
+	},
+	{
+		Label: "Instantiates",
+		From:  []string{"functioncall", "statement", "variable", "field"},
+		To:    []string{"type", "typeparam"},
+		Properties: map[string]interface{}{
+			"index":   0,
+			"generic": "",
+			"typeArg": "",
+		},
+	},
+}
@@ -50,7 +50,24 @@ func (p *Package) Properties() map[string]interface{} {
 
 type Function struct {
 	vertexBase
-	Name           string
+	Name string
+	// receiver type for methods (e.g. "*T"), empty for plain functions
+	Receiver string
+	// fully qualified name, unique within a package (e.g. "example.com/pkg.(*T).M").
+	// Like the runtime does, init (and _) functions are numbered in declaration order: "example.com/pkg.init.0" (_
+	// methods per receiver, "example.com/pkg.T._.0"), and
+	// function literals are named after the function they're in: "example.com/pkg.F.func1"
+	Symbol string
+	// e.g. "func(w io.Writer, n int) error"
+	Signature string
+	// the doc comment's text, without any directives (see the directive vertex)
+	Doc string
+	// from the func keyword to the closing brace (or the end of the signature, for functions without a body).
+	// 1-based, columns are in bytes
+	StartLine      int
+	StartColumn    int
+	EndLine        int
+	EndColumn      int
 	Package        *Package        `json:"-"`
 	FirstStatement *Statement      `json:"-"`
 	Statements     []*Statement    `json:"-"`
@@ -63,7 +80,15 @@ func (_ *Function) Label() string {
 
 func (f *Function) Properties() map[string]interface{} {
 	return map[string]interface{}{
-		"Name": f.Name,
+		"Name":        f.Name,
+		"Receiver":    f.Receiver,
+		"Symbol":      f.Symbol,
+		"Signature":   f.Signature,
+		"Doc":         f.Doc,
+		"StartLine":   f.StartLine,
+		"StartColumn": f.StartColumn,
+		"EndLine":     f.EndLine,
+		"EndColumn":   f.EndColumn,
 	}
 }
 
@@ -86,13 +111,19 @@ func (v *Variable) Properties() map[string]interface{} {
 
 type Statement struct {
 	vertexBase
-	File       string
-	Offset     int
-	Text       string
-	ASTType    string
-	Next       []*Statement `json:"-"`
-	References []*Variable  `json:"-"`
-	Assigns    []*Variable  `json:"-"`
+	// absolute path on the machine which did the ingest, see the file vertex for a stable one
+	File   string
+	Offset int
+	// 1-based, columns are in bytes. End is the position just after the statement
+	StartLine   int
+	StartColumn int
+	EndLine     int
+	EndColumn   int
+	Text        string
+	ASTType     string
+	Next        []*Statement `json:"-"`
+	References  []*Variable  `json:"-"`
+	Assigns     []*Variable  `json:"-"`
 }
 
 func (_ *Statement) Label() string {
@@ -101,15 +132,21 @@ func (_ *Statement) Label() string {
 
 func (s *Statement) Properties() map[string]interface{} {
 	return map[string]interface{}{
-		"File":    s.File,
-		"Offset":  s.Offset,
-		"Text":    s.Text,
-		"ASTType": s.ASTType,
+		"File":        s.File,
+		"Offset":      s.Offset,
+		"StartLine":   s.StartLine,
+		"StartColumn": s.StartColumn,
+		"EndLine":     s.EndLine,
+		"EndColumn":   s.EndColumn,
+		"Text":        s.Text,
+		"ASTType":     s.ASTType,
 	}
 }
 
 type FunctionCall struct {
 	vertexBase
+	// call, go or defer
+	Kind   string
 	Caller *Function   `json:"-"`
 	Callee *Function   `json:"-"`
 	Args   []*Variable `json:"-"`
@@ -119,3 +156,151 @@ type FunctionCall struct {
 func (_ *FunctionCall) Label() string {
 	return "functioncall"
 }
+
+func (fc *FunctionCall) Properties() map[string]interface{} {
+	return map[string]interface{}{
+		"Kind": fc.Kind,
+	}
+}
+
+// Type is a package level named type.
+// Type arguments which don't have a vertex of their own (e.g. int, []byte or func()) get one too, in the package
+// "builtin" with no version, with their type string as both Name and Symbol. If they use types declared inside a
+// function, where those are declared is appended to the Symbol (e.g. "[]example.com/pkg.T·file.go:12") so it doesn't
+// look like a package level type's.
+type Type struct {
+	vertexBase
+	Name string
+	// e.g. "example.com/pkg.T"
+	Symbol string
+	// struct, interface, alias, basic, pointer, slice, array, map, chan or func
+	Kind string
+	// what the type is defined as (or an alias of), e.g. "struct{mu sync.Mutex; n int}"
+	Underlying string
+}
+
+func (_ *Type) Label() string {
+	return "type"
+}
+
+func (t *Type) Properties() map[string]interface{} {
+	return map[string]interface{}{
+		"Name":       t.Name,
+		"Symbol":     t.Symbol,
+		"Kind":       t.Kind,
+		"Underlying": t.Underlying,
+	}
+}
+
+// Field is a struct field, from either a named or an anonymous struct type
+type Field struct {
+	vertexBase
+	Name     string
+	Type     string
+	Embedded bool
+	// position in the struct
+	Index int
+	Tag   string
+}
+
+func (_ *Field) Label() string {
+	return "field"
+}
+
+func (f *Field) Properties() map[string]interface{} {
+	return map[string]interface{}{
+		"Name":     f.Name,
+		"Type":     f.Type,
+		"Embedded": f.Embedded,
+		"Index":    f.Index,
+		"Tag":      f.Tag,
+	}
+}
+
+// File is a source file of a package
+type File struct {
+	vertexBase
+	// relative to the root of its module (or GOROOT/src for the standard library), e.g. "sub/pkg/file.go"
+	Path    string
+	Package string
+	// module path, "std" for the standard library
+	Module string
+	// hex encoded sha256 of the contents
+	Hash string
+}
+
+func (_ *File) Label() string {
+	return "file"
+}
+
+func (f *File) Properties() map[string]interface{} {
+	return map[string]interface{}{
+		"Path":    f.Path,
+		"Package": f.Package,
+		"Module":  f.Module,
+		"Hash":    f.Hash,
+	}
+}
+
+// Constant is a named constant, either package level or local to a function
+type Constant struct {
+	vertexBase
+	Name string
+	// e.g. "example.com/pkg.C", empty for local constants
+	Symbol string
+	Type   string
+	// the exact value for integers, booleans and strings (quoted), and an approximation for floats
+	Value string
+}
+
+func (_ *Constant) Label() string {
+	return "constant"
+}
+
+func (c *Constant) Properties() map[string]interface{} {
+	return map[string]interface{}{
+		"Name":   c.Name,
+		"Symbol": c.Symbol,
+		"Type":   c.Type,
+		"Value":  c.Value,
+	}
+}
+
+// TypeParam is a type parameter of a generic function or type, linked from it by a TypeParams edge
+type TypeParam struct {
+	vertexBase
+	Name string
+	// e.g. "any", "fmt.Stringer" or "~int | ~string"
+	Constraint string
+}
+
+func (_ *TypeParam) Label() string {
+	return "typeparam"
+}
+
+func (t *TypeParam) Properties() map[string]interface{} {
+	return map[string]interface{}{
+		"Name":       t.Name,
+		"Constraint": t.Constraint,
+	}
+}
+
+// Directive is a //go: compiler directive on a function, e.g. //go:linkname nanotime runtime.nanotime
+type Directive struct {
+	vertexBase
+	// e.g. "linkname", "noinline"
+	Name string
+	// everything after the name, e.g. "nanotime runtime.nanotime"
+	Args string
+}
+
+func (_ *Directive) Label() string {
+	return "directive"
+}
+
+func (d *Directive) Properties() map[string]interface{} {
+	return map[string]interface{}{
+		"Name": d.Name,
+		"Args": d.Args,
+	}
+}
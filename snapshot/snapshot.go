@@ -0,0 +1,262 @@
+// Package snapshot dumps a whole graph to, and restores it from, a gzipped JSON Lines file.
+//
+// The first line is a header carrying the schema version the dump was made with. Every vertex line comes before every
+// edge line, and edges refer to vertices by their (dump-local) id.
+package snapshot
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/sirupsen/logrus"
+
+	gbackend "github.com/kallsyms/go-graph/backend"
+	"github.com/kallsyms/go-graph/coordination"
+	"github.com/kallsyms/go-graph/schema"
+)
+
+const FORMAT = "go-graph-dump"
+
+// edges are buffered and handed to AddEBulk in chunks this big
+const RESTORE_EDGE_CHUNK = 100000
+
+type header struct {
+	Format        string `json:"format"`
+	SchemaVersion int    `json:"schemaVersion"`
+}
+
+type record struct {
+	Type       string                 `json:"type"`
+	Label      string                 `json:"label"`
+	ID         uint64                 `json:"id,omitempty"`
+	From       uint64                 `json:"from,omitempty"`
+	To         uint64                 `json:"to,omitempty"`
+	Properties map[string]interface{} `json:"properties,omitempty"`
+}
+
+// Dump writes every vertex and edge in src to out.
+func Dump(out io.Writer, src gbackend.Enumerator) error {
+	gz := gzip.NewWriter(out)
+	w := bufio.NewWriter(gz)
+	enc := json.NewEncoder(w)
+
+	if err := enc.Encode(header{FORMAT, schema.SchemaVersion}); err != nil {
+		return err
+	}
+
+	ids := map[interface{}]uint64{}
+
+	vertexCount := 0
+	err := src.ScanVertices(func(v schema.Vertex) error {
+		id := uint64(len(ids) + 1)
+		ids[v.GetBackendMeta()] = id
+		vertexCount++
+
+		return enc.Encode(record{
+			Type:       "vertex",
+			Label:      v.Label(),
+			ID:         id,
+			Properties: v.Properties(),
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("Error dumping vertices: %v", err)
+	}
+
+	edgeCount := 0
+	err = src.ScanEdges(func(e schema.Edge) error {
+		from, ok := ids[e.Source.GetBackendMeta()]
+		if !ok {
+			return fmt.Errorf("%q edge from unknown vertex %v", e.Label, e.Source.GetBackendMeta())
+		}
+		to, ok := ids[e.Target.GetBackendMeta()]
+		if !ok {
+			return fmt.Errorf("%q edge to unknown vertex %v", e.Label, e.Target.GetBackendMeta())
+		}
+		edgeCount++
+
+		return enc.Encode(record{
+			Type:       "edge",
+			Label:      e.Label,
+			From:       from,
+			To:         to,
+			Properties: e.Properties,
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("Error dumping edges: %v", err)
+	}
+
+	logrus.Infof("Dumped %d vertices and %d edges", vertexCount, edgeCount)
+
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// normalizeNumbers turns json.Numbers back into the ints (or floats) the ingester would have created
+func normalizeNumbers(props map[string]interface{}) {
+	for k, v := range props {
+		if n, ok := v.(json.Number); ok {
+			if i, err := n.Int64(); err == nil {
+				props[k] = int(i)
+			} else if f, err := n.Float64(); err == nil {
+				props[k] = f
+			}
+		}
+	}
+}
+
+func decodeVertex(rec record) (schema.Vertex, error) {
+	v, err := schema.NewVertex(rec.Label)
+	if err != nil {
+		return nil, err
+	}
+
+	buf, err := json.Marshal(rec.Properties)
+	if err != nil {
+		return nil, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(buf))
+	dec.UseNumber()
+	if err := dec.Decode(v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// Restore loads a dump made by Dump into dst.
+// Packages are created with CreatePackage, so restoring a package which is already in dst fails.
+func Restore(in io.Reader, dst gbackend.Backend) error {
+	gz, err := gzip.NewReader(in)
+	if err != nil {
+		return fmt.Errorf("Not a go-graph dump: %v", err)
+	}
+	defer gz.Close()
+
+	dec := json.NewDecoder(bufio.NewReader(gz))
+	dec.UseNumber()
+
+	var hdr header
+	if err := dec.Decode(&hdr); err != nil || hdr.Format != FORMAT {
+		return fmt.Errorf("Not a go-graph dump (bad header)")
+	}
+	if hdr.SchemaVersion != schema.SchemaVersion {
+		return fmt.Errorf("Dump has schema version %d, but this go-graph uses schema version %d", hdr.SchemaVersion, schema.SchemaVersion)
+	}
+
+	vertices := map[uint64]schema.Vertex{}
+
+	vertexStream := make(chan schema.Vertex, 32768)
+	var vertexCount int
+	var countMtx sync.Mutex
+	vertexWorkers := dst.AddVStream(vertexStream, func(done []schema.Vertex) {
+		countMtx.Lock()
+		vertexCount += len(done)
+		countMtx.Unlock()
+	})
+
+	edges := []schema.Edge{}
+	edgeCount := 0
+	flushEdges := func() error {
+		if err := dst.AddEBulk(edges, func([]schema.Edge) {}); err != nil {
+			return fmt.Errorf("Error adding edges: %v", err)
+		}
+		edgeCount += len(edges)
+		edges = []schema.Edge{}
+		return nil
+	}
+
+	// all vertices need their backend meta before any edges can be added
+	verticesDone := false
+	finishVertices := func() error {
+		if verticesDone {
+			return nil
+		}
+		close(vertexStream)
+		verticesDone = true
+		if err := vertexWorkers.Wait(); err != nil {
+			return fmt.Errorf("Error adding vertices: %v", err)
+		}
+		return nil
+	}
+	defer finishVertices()
+
+	for {
+		var rec record
+		err := dec.Decode(&rec)
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return fmt.Errorf("Error reading dump: %v", err)
+		}
+		normalizeNumbers(rec.Properties)
+
+		switch rec.Type {
+		case "vertex":
+			if verticesDone {
+				return fmt.Errorf("Vertex %d after edges in dump", rec.ID)
+			}
+
+			v, err := decodeVertex(rec)
+			if err != nil {
+				return fmt.Errorf("Error decoding vertex %d: %v", rec.ID, err)
+			}
+
+			// packages have to go through CreatePackage so that backends can keep track of them
+			if pkg, ok := v.(*schema.Package); ok {
+				created, err := dst.CreatePackage(coordination.PackageTuple{Name: pkg.SourceURL, Version: pkg.Version})
+				if err != nil {
+					return fmt.Errorf("Error creating package %s@%s: %v", pkg.SourceURL, pkg.Version, err)
+				}
+				v = created
+				countMtx.Lock()
+				vertexCount++
+				countMtx.Unlock()
+			} else {
+				vertexStream <- v
+			}
+			vertices[rec.ID] = v
+
+		case "edge":
+			if err := finishVertices(); err != nil {
+				return err
+			}
+
+			from, ok := vertices[rec.From]
+			if !ok {
+				return fmt.Errorf("%q edge from unknown vertex %d", rec.Label, rec.From)
+			}
+			to, ok := vertices[rec.To]
+			if !ok {
+				return fmt.Errorf("%q edge to unknown vertex %d", rec.Label, rec.To)
+			}
+
+			edges = append(edges, schema.Edge{Source: from, Label: rec.Label, Target: to, Properties: rec.Properties})
+			if len(edges) >= RESTORE_EDGE_CHUNK {
+				if err := flushEdges(); err != nil {
+					return err
+				}
+			}
+
+		default:
+			return fmt.Errorf("Unknown record type %q in dump", rec.Type)
+		}
+	}
+
+	if err := finishVertices(); err != nil {
+		return err
+	}
+	if err := flushEdges(); err != nil {
+		return err
+	}
+
+	logrus.Infof("Restored %d vertices and %d edges", vertexCount, edgeCount)
+	return nil
+}
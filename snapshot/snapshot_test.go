@@ -0,0 +1,158 @@
+package snapshot
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	gbackend "github.com/kallsyms/go-graph/backend"
+	"github.com/kallsyms/go-graph/coordination"
+	"github.com/kallsyms/go-graph/schema"
+)
+
+// testGraph stores a function with a small loop, and a call passing x as its second argument
+func testGraph(t *testing.T) *gbackend.MemoryBackend {
+	backend := gbackend.NewMemoryBackend()
+	pkg, err := backend.CreatePackage(coordination.PackageTuple{Name: "example.com/p", Version: "v1.0.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run := &schema.Function{Name: "Run", Symbol: "example.com/p.Run", Signature: "func(x int)", StartLine: 3, EndLine: 9}
+	x := &schema.Variable{Name: "x", Type: "int"}
+	call := &schema.FunctionCall{Kind: "defer"}
+	stmts := []*schema.Statement{}
+	vertices := []schema.Vertex{run, x, call}
+	for i := 0; i < 3; i++ {
+		s := &schema.Statement{File: "p.go", Offset: i * 10, StartLine: i + 4, Text: fmt.Sprintf("s%d", i), ASTType: "*ast.ExprStmt"}
+		stmts = append(stmts, s)
+		vertices = append(vertices, s)
+	}
+
+	stream := make(chan schema.Vertex)
+	workers := backend.AddVStream(stream, func([]schema.Vertex) {})
+	for _, v := range vertices {
+		stream <- v
+	}
+	close(stream)
+	if err := workers.Wait(); err != nil {
+		t.Fatal(err)
+	}
+
+	next := func(from, to int, back bool) schema.Edge {
+		return schema.Edge{Source: stmts[from], Label: "Next", Target: stmts[to], Properties: map[string]interface{}{"isBackEdge": back, "selectCase": ""}}
+	}
+	edges := []schema.Edge{
+		{Source: pkg, Label: "Functions", Target: run},
+		{Source: run, Label: "FirstStatement", Target: stmts[0]},
+		{Source: run, Label: "Calls", Target: call},
+		{Source: call, Label: "CallSiteStatement", Target: stmts[1]},
+		{Source: call, Label: "Arg", Target: x, Properties: map[string]interface{}{"index": 1}},
+		next(0, 1, false),
+		next(1, 2, false),
+		next(2, 1, true),
+	}
+	if err := backend.AddEBulk(edges, func([]schema.Edge) {}); err != nil {
+		t.Fatal(err)
+	}
+
+	return backend
+}
+
+// contents describes every vertex and edge in backend, including the Go types of their properties
+func contents(t *testing.T, backend *gbackend.MemoryBackend) (vertices []string, edges []string) {
+	err := backend.ScanVertices(func(v schema.Vertex) error {
+		vertices = append(vertices, fmt.Sprintf("%s %#v", v.Label(), v.Properties()))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = backend.ScanEdges(func(e schema.Edge) error {
+		edges = append(edges, fmt.Sprintf("%#v -%s %#v-> %#v", e.Source.Properties(), e.Label, e.Properties, e.Target.Properties()))
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sort.Strings(vertices)
+	sort.Strings(edges)
+	return vertices, edges
+}
+
+func TestRoundTrip(t *testing.T) {
+	src := testGraph(t)
+
+	var buf bytes.Buffer
+	if err := Dump(&buf, src); err != nil {
+		t.Fatal(err)
+	}
+	dst := gbackend.NewMemoryBackend()
+	if err := Restore(&buf, dst); err != nil {
+		t.Fatal(err)
+	}
+
+	wantVertices, wantEdges := contents(t, src)
+	gotVertices, gotEdges := contents(t, dst)
+	if len(gotVertices) != len(wantVertices) || len(gotEdges) != len(wantEdges) {
+		t.Errorf("Restored %d vertices and %d edges, want %d and %d", len(gotVertices), len(gotEdges), len(wantVertices), len(wantEdges))
+	}
+	if !reflect.DeepEqual(gotVertices, wantVertices) {
+		t.Errorf("Restored vertices:\n%s\nwant:\n%s", strings.Join(gotVertices, "\n"), strings.Join(wantVertices, "\n"))
+	}
+	if !reflect.DeepEqual(gotEdges, wantEdges) {
+		t.Errorf("Restored edges:\n%s\nwant:\n%s", strings.Join(gotEdges, "\n"), strings.Join(wantEdges, "\n"))
+	}
+
+	// the Arg index has to come back as an int (not a json.Number or float64), like the ingester makes it
+	args := dst.V("functioncall").OutWhere(func(e schema.Edge) bool {
+		index, ok := e.Properties["index"].(int)
+		return ok && index == 1
+	}, "Arg").ToList()
+	if len(args) != 1 {
+		t.Errorf("No Arg edge with int index 1 after restoring")
+	}
+
+	if _, found, _ := dst.GetPackage(coordination.PackageTuple{Name: "example.com/p", Version: "v1.0.0"}); !found {
+		t.Errorf("Restored package isn't found by GetPackage")
+	}
+}
+
+// dumpWithHeader makes a dump which only has the given header line
+func dumpWithHeader(t *testing.T, hdr interface{}) *bytes.Buffer {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := json.NewEncoder(gz).Encode(hdr); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestRestoreOldSchemaVersion(t *testing.T) {
+	err := Restore(dumpWithHeader(t, header{FORMAT, schema.SchemaVersion - 1}), gbackend.NewMemoryBackend())
+	if err == nil {
+		t.Fatal("Restored a dump from an older schema version")
+	}
+	want := fmt.Sprintf("Dump has schema version %d, but this go-graph uses schema version %d", schema.SchemaVersion-1, schema.SchemaVersion)
+	if err.Error() != want {
+		t.Errorf("Restore error = %q, want %q", err, want)
+	}
+}
+
+func TestRestoreNotADump(t *testing.T) {
+	if err := Restore(strings.NewReader("{}"), gbackend.NewMemoryBackend()); err == nil {
+		t.Error("Restored something which isn't gzipped")
+	}
+	if err := Restore(dumpWithHeader(t, map[string]string{"format": "something-else"}), gbackend.NewMemoryBackend()); err == nil {
+		t.Error("Restored a dump with the wrong format")
+	}
+}
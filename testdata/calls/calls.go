@@ -0,0 +1,17 @@
+package calls
+
+const Limit = 5
+
+func pair(a, b int) (int, error) {
+	return a + b, nil
+}
+
+func Use(x int) int {
+	sum, err := pair(x, Limit)
+	if err != nil {
+		return 0
+	}
+	var total int
+	total, _ = pair(sum, x)
+	return total
+}
@@ -0,0 +1,31 @@
+package channels
+
+type Worker struct {
+	jobs chan int
+}
+
+func Produce(out chan<- int, n int) {
+	for i := 0; i < n; i++ {
+		out <- i
+	}
+	close(out)
+}
+
+func Consume(in <-chan int) int {
+	sum := 0
+	for v := range in {
+		sum += v
+	}
+	return sum
+}
+
+func (w *Worker) Next(done chan struct{}) int {
+	select {
+	case j := <-w.jobs:
+		return j
+	case <-done:
+		return -1
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,24 @@
+package closures
+
+func Counter() func() int {
+	n := 0
+	inc := func() int {
+		n++
+		return n
+	}
+	return inc
+}
+
+func Nested(x int) int {
+	f := func() int {
+		g := func() int { return x }
+		return g()
+	}
+	return f()
+}
+
+func Local() int {
+	return func(y int) int { return y * 2 }(1)
+}
+
+var Global = func(s string) string { return s }
@@ -0,0 +1,25 @@
+package constants
+
+import "math"
+
+const Max = 10
+
+const (
+	A = iota
+	B
+)
+
+type Mode int
+
+const Fast Mode = 1
+
+const Greeting = "hi"
+
+func Check(n int) bool {
+	const local = 3
+	if n > Max {
+		return false
+	}
+	limit := math.MaxInt8
+	return n != local && n < limit && Mode(n) != Fast
+}
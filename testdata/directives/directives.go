@@ -0,0 +1,25 @@
+package directives
+
+import (
+	_ "unsafe" // for go:linkname
+)
+
+// Add adds a and b.
+//
+// Deprecated: use + instead.
+//
+//go:noinline
+func Add(a, b int) int {
+	return a + b
+}
+
+//go:nosplit
+//go:norace
+func fast() {}
+
+func nanotime() int64
+
+//go:linkname nanotime runtime.nanotime
+
+// Plain has no directives.
+func Plain() {}
@@ -0,0 +1,17 @@
+package fieldarg
+
+type config struct {
+	Timeout int
+}
+
+type server struct {
+	conf config
+}
+
+func wait(timeout int) {
+	_ = timeout
+}
+
+func Wait(s *server) {
+	wait(s.conf.Timeout)
+}
@@ -0,0 +1,3 @@
+module example.com/fieldarg
+
+go 1.17
@@ -0,0 +1,20 @@
+package fields
+
+type Inner struct {
+	Count int
+}
+
+type Outer struct {
+	Inner
+	Label string
+	Total int
+}
+
+func Update(o *Outer) (string, Inner) {
+	o.Label = "updated"
+	o.Total += o.Count
+	o.Count++
+	o.Inner.Count = 2
+	c := Inner{Count: 3}
+	return o.Label, c
+}
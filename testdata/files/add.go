@@ -0,0 +1,6 @@
+package files
+
+func Add(a, b int) int {
+	sum := a + b
+	return sum
+}
@@ -0,0 +1,4 @@
+package files
+
+// Twice is in another file
+func Twice(n int) int { return Add(n, n) }
@@ -0,0 +1,28 @@
+package implements
+
+import "fmt"
+
+type Closer interface {
+	Close() error
+}
+
+type ReadCloser interface {
+	Closer
+	Read(p []byte) (int, error)
+}
+
+type Any interface{}
+
+type File struct{}
+
+func (File) Close() error { return nil }
+
+func (File) String() string { return "file" }
+
+type Conn struct{}
+
+func (*Conn) Close() error { return nil }
+
+func (*Conn) Read(p []byte) (int, error) { return 0, nil }
+
+var _ fmt.Stringer = File{}
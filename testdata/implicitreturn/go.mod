@@ -0,0 +1,3 @@
+module example.com/implicitreturn
+
+go 1.17
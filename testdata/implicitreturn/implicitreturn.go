@@ -0,0 +1,20 @@
+package implicitreturn
+
+var counter int
+
+func Incr() {
+	counter++
+}
+
+func Add(n int) int {
+	counter += n
+	return counter
+}
+
+func Loop(n int) {
+	for i := 0; i < n; i++ {
+		func() {
+			counter++
+		}()
+	}
+}
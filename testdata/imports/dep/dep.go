@@ -0,0 +1,7 @@
+package dep
+
+import "example.com/dep/inner"
+
+func Answer() int {
+	return inner.Value
+}
@@ -0,0 +1,3 @@
+package inner
+
+const Value = 42
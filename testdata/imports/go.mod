@@ -0,0 +1,7 @@
+module example.com/imports
+
+go 1.17
+
+require example.com/dep v1.2.3
+
+replace example.com/dep => ./dep
@@ -0,0 +1,10 @@
+package imports
+
+import (
+	"example.com/dep"
+	"example.com/imports/sub"
+)
+
+func Run() int {
+	return dep.Answer() + sub.Double(1)
+}
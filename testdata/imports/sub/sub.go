@@ -0,0 +1,7 @@
+package sub
+
+import "example.com/dep"
+
+func Double(n int) int {
+	return n * dep.Answer()
+}
@@ -0,0 +1,8 @@
+package initorder
+
+// initialized after B, which it depends on
+var A = B + 1
+
+func init() {
+	A++
+}
@@ -0,0 +1,19 @@
+package initorder
+
+var B = compute()
+
+var unset int
+
+var x, y = pair()
+
+func compute() int {
+	return 1
+}
+
+func pair() (int, int) {
+	return 1, 2
+}
+
+func init() {
+	unset = x + y
+}
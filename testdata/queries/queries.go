@@ -0,0 +1,37 @@
+package queries
+
+type T struct{}
+
+func (t *T) Close() {}
+
+type U struct{}
+
+func (u *U) Close() {}
+
+func CloseBoth(t *T, u *U) {
+	t.Close()
+	u.Close()
+}
+
+// A calls b calls c calls leaf
+func A() {
+	b()
+}
+
+func b() {
+	c()
+}
+
+func c() {
+	leaf()
+}
+
+func leaf() {}
+
+func Sum(n int) int {
+	x := 0
+	for i := 0; i < n; i++ {
+		x += i
+	}
+	return x
+}
@@ -0,0 +1,21 @@
+package signatures
+
+func Divide(num, den int) (quot, rem int) {
+	quot = num / den
+	rem = num % den
+	return
+}
+
+type Calc struct{}
+
+func (c *Calc) Apply(f func(int) int, x int) int {
+	return f(x)
+}
+
+func Twice(n int) int {
+	return new(Calc).Apply(func(y int) int { return y * 2 }, n)
+}
+
+func Ignore(int, _ string) error {
+	return nil
+}
@@ -0,0 +1,3 @@
+module example.com/spawns
+
+go 1.17
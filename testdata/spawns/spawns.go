@@ -0,0 +1,15 @@
+package spawns
+
+func work(n int) {}
+
+func cleanup() {}
+
+func Run() {
+	defer cleanup()
+	go work(1)
+	work(2)
+	go func() {
+		work(3)
+	}()
+	defer func() {}()
+}
@@ -0,0 +1,29 @@
+package symbols
+
+type T struct{}
+
+func (T) Close() error { return nil }
+
+func (T) String() string { return "T" }
+
+func (T) _() {}
+
+func (T) _() {}
+
+type U struct{}
+
+func (*U) Close() error { return nil }
+
+func (u *U) String() string { return "U" }
+
+func (*U) _() {}
+
+type List[E any] struct{}
+
+func (l *List[E]) Len() int { return 0 }
+
+func init() {}
+
+func init() {}
+
+func _() {}
@@ -0,0 +1,8 @@
+package again
+
+func Zero[T any]() T {
+	var z T
+	return z
+}
+
+var N = Zero[int]()
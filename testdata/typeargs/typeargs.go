@@ -0,0 +1,31 @@
+package typeargs
+
+type T struct{}
+
+type List[E any] struct {
+	items []E
+}
+
+type Map[K comparable, V any] struct {
+	m map[K]V
+}
+
+func F[X any](x X) X {
+	return x
+}
+
+func Use() {
+	var ints List[int]
+	var bytes Map[string, []byte]
+	_, _ = ints, bytes
+	F[*struct{}](nil)
+	F[func()](nil)
+	F[chan T](nil)
+	F(&T{})
+}
+
+func Local() {
+	type T struct{ n int }
+	F(T{})
+	F([]T{})
+}
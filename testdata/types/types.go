@@ -0,0 +1,26 @@
+package types
+
+type Base struct {
+	ID int
+}
+
+type Config struct {
+	Timeout int
+}
+
+type Server struct {
+	Base
+	Name  string `json:"name"`
+	Peers []*Server
+	conf  *Config
+}
+
+func (s *Server) Start() {}
+
+func (s Server) Addr() string {
+	return s.Name
+}
+
+type Port int
+
+var DefaultPort Port = 80